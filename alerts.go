@@ -0,0 +1,285 @@
+package health
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// webhookTimeout bounds how long an alert webhook POST can take.
+const webhookTimeout = 10 * time.Second
+
+// alertQueueSize is how many webhook deliveries can be waiting before new
+// ones are dropped.
+const alertQueueSize = 100
+
+// AlertRule fires when the average of a rolling metric meets a condition,
+// for example Metric "response_time", Op ">", Threshold 500, Window 5m.
+// With no Window the rolling average is used.
+//
+// Like SlidingStats, a Window only sees the points held in the rolling
+// sample, so under load it covers less time than configured unless the
+// rolling data size is large enough to hold the window.
+type AlertRule struct {
+	Name      string
+	Metric    string
+	Op        string // one of >, >=, <, <=
+	Threshold float64
+	Window    time.Duration
+	Webhook   string // URL to POST transitions to, optional
+}
+
+// UnmarshalJSON reads an AlertRule with Window given as a duration string,
+// such as "5m", or as a number of nanoseconds.
+func (rule *AlertRule) UnmarshalJSON(data []byte) error {
+
+	type plain AlertRule
+	aux := struct {
+		*plain
+		Window json.RawMessage
+	}{plain: (*plain)(rule)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	window := strings.TrimSpace(string(aux.Window))
+	switch {
+	case len(window) == 0 || window == "null":
+		rule.Window = 0
+	case strings.HasPrefix(window, `"`):
+		var text string
+		if err := json.Unmarshal(aux.Window, &text); err != nil {
+			return err
+		}
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("alert rule %s: invalid Window: %s", rule.Name, err)
+		}
+		rule.Window = d
+	default:
+		var ns int64
+		if err := json.Unmarshal(aux.Window, &ns); err != nil {
+			return fmt.Errorf("alert rule %s: invalid Window: %s", rule.Name, err)
+		}
+		rule.Window = time.Duration(ns)
+	}
+
+	return nil
+}
+
+// AlertEvent is the JSON body POSTed to a rule's webhook when the rule
+// transitions to firing or resolved.
+type AlertEvent struct {
+	Identity string
+	Rule     string
+	Metric   string
+	State    string // firing or resolved
+	Value    float64
+	Time     int64
+}
+
+type alert struct {
+	rule   AlertRule
+	firing bool
+}
+
+type alertDelivery struct {
+	logger Logger
+	url    string
+	event  AlertEvent
+}
+
+// AddAlertRule adds a rule, replacing any existing rule with the same name.
+func (s *State) AddAlertRule(rule AlertRule) error {
+
+	if len(rule.Name) < 1 || len(rule.Metric) < 1 {
+		return errors.New("alert rule needs a name and a metric")
+	}
+
+	switch rule.Op {
+	case ">", ">=", "<", "<=":
+	default:
+		return errors.New("alert rule op must be one of >, >=, <, <=")
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	if s.alerts == nil {
+		s.alerts = make(map[string]*alert)
+	}
+
+	s.alerts[rule.Name] = &alert{rule: rule}
+	mu.Unlock() // end CRITICAL SECTION
+
+	return nil
+}
+
+// LoadAlertRules adds rules from a JSON array of AlertRule objects, with
+// Window given as a duration string such as "5m".
+func (s *State) LoadAlertRules(r io.Reader) error {
+
+	var rules []AlertRule
+	if err := json.NewDecoder(r).Decode(&rules); err != nil {
+		return err
+	}
+
+	for _, rule := range rules {
+		if err := s.AddAlertRule(rule); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// evaluateAlerts checks the rules for metric, and queues the webhooks for
+// any rule that changed between firing and resolved. Webhooks are posted
+// in order by a single goroutine, so a receiver never sees resolved before
+// firing.
+func (s *State) evaluateAlerts(metric string) {
+
+	var published []EventInfo
+	var dropped []error
+
+	mu.Lock() // enter CRITICAL SECTION
+	if len(s.Maintenance) > 0 {
+		mu.Unlock() // planned work, don't alert
+		return
+	}
+
+	data := s.rollingMetricsData[metric]
+	for _, a := range s.alerts {
+		if a.rule.Metric != metric || data == nil {
+			continue
+		}
+
+		value := s.RollingMetrics[metric]
+		if a.rule.Window > 0 {
+			value = average(data.Since(time.Now().Add(-a.rule.Window)))
+		}
+
+		firing := a.rule.matches(value)
+		if firing == a.firing {
+			continue
+		}
+		a.firing = firing
+
+		event := AlertEvent{
+			Identity: s.Identity,
+			Rule:     a.rule.Name,
+			Metric:   metric,
+			State:    "resolved",
+			Value:    value,
+			Time:     time.Now().Unix(),
+		}
+		info := EventInfo{Event: EventThresholdResolved, Name: a.rule.Name, Value: value, Time: event.Time}
+		if firing {
+			event.State = "firing"
+			info.Event = EventThresholdBreached
+			s.incrInternal(internalAlertsFired, 1)
+		}
+		published = append(published, info)
+
+		if len(a.rule.Webhook) > 0 {
+			if err := s.queueAlert(alertDelivery{logger: s.logger, url: a.rule.Webhook, event: event}); err != nil {
+				dropped = append(dropped, err)
+			}
+		}
+	}
+	mu.Unlock() // end CRITICAL SECTION
+
+	for _, err := range dropped {
+		s.logf("%s", err)
+	}
+
+	for _, info := range published {
+		s.publish(info)
+	}
+}
+
+// queueAlert queues a webhook delivery, starting the delivery goroutine
+// on first use, or returns an error if the delivery was dropped. Must be
+// called from within the critical section.
+func (s *State) queueAlert(d alertDelivery) error {
+
+	if s.shutdown {
+		return fmt.Errorf("health: alert %s webhook dropped, shutting down", d.event.Rule)
+	}
+
+	if s.alertQueue == nil {
+		s.alertQueue = make(chan alertDelivery, alertQueueSize)
+		s.alertDone = make(chan struct{})
+		go s.deliverAlerts(s.alertQueue, s.alertDone)
+	}
+
+	select {
+	case s.alertQueue <- d:
+		return nil
+	default:
+		return fmt.Errorf("health: alert %s webhook dropped, queue full", d.event.Rule)
+	}
+}
+
+func (s *State) deliverAlerts(queue <-chan alertDelivery, done chan<- struct{}) {
+
+	defer close(done)
+
+	for d := range queue {
+		if err := postAlert(d.url, d.event); err != nil {
+			logf(d.logger, "health: alert %s webhook failed: %s", d.event.Rule, err)
+			s.recordInternal(internalAlertWebhookErrors, 1)
+		}
+	}
+}
+
+func (rule AlertRule) matches(value float64) bool {
+
+	switch rule.Op {
+	case ">":
+		return value > rule.Threshold
+	case ">=":
+		return value >= rule.Threshold
+	case "<":
+		return value < rule.Threshold
+	case "<=":
+		return value <= rule.Threshold
+	}
+	return false
+}
+
+func average(values []float64) float64 {
+
+	if len(values) == 0 {
+		return 0
+	}
+
+	var total float64
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+func postAlert(url string, event AlertEvent) error {
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return errors.New(strings.ToLower(resp.Status))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAddAlertRuleValidation(t *testing.T) {
+	// Test rules without a name, metric or valid op are rejected.
+	//
+	var s State
+	s.Info("test", 10)
+
+	if err := s.AddAlertRule(AlertRule{Metric: "latency", Op: ">"}); err == nil {
+		t.Errorf("Alert rule without a name should be rejected")
+	}
+
+	if err := s.AddAlertRule(AlertRule{Name: "slow", Metric: "latency", Op: "=="}); err == nil {
+		t.Errorf("Alert rule with a bad op should be rejected")
+	}
+}
+
+func TestAlertFiresAndResolves(t *testing.T) {
+	// Test the webhook is called once on firing and once on resolving,
+	// not on every data point.
+	events := make(chan AlertEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event AlertEvent
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	defer server.Close()
+
+	var s State
+	s.Info("test", 1)
+	err := s.AddAlertRule(AlertRule{
+		Name:      "slow",
+		Metric:    "latency",
+		Op:        ">",
+		Threshold: 500,
+		Webhook:   server.URL,
+	})
+	if err != nil {
+		t.Fatalf("AddAlertRule returned error: %s", err)
+	}
+
+	s.UpdateRollingMetric("latency", 600)
+	s.UpdateRollingMetric("latency", 700)
+	s.UpdateRollingMetric("latency", 100)
+
+	for _, want := range []string{"firing", "resolved"} {
+		select {
+		case event := <-events:
+			if event.State != want || event.Rule != "slow" {
+				t.Errorf("Alert expected %s event, got: %+v", want, event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Alert webhook not called for %s", want)
+		}
+	}
+
+	if s.HealthInternal[internalAlertsFired] != 1 {
+		t.Errorf("Alert fired counter expected 1, got: %f", s.HealthInternal[internalAlertsFired])
+	}
+}
+
+func TestLoadAlertRules(t *testing.T) {
+	// Test rules can be loaded from JSON config.
+	//
+	var s State
+	s.Info("test", 1)
+
+	config := `[{"Name": "errors", "Metric": "error_rate", "Op": ">=", "Threshold": 1}]`
+	if err := s.LoadAlertRules(strings.NewReader(config)); err != nil {
+		t.Fatalf("LoadAlertRules returned error: %s", err)
+	}
+
+	s.UpdateRollingMetric("error_rate", 1)
+	if s.HealthInternal[internalAlertsFired] != 1 {
+		t.Errorf("Loaded alert rule did not fire")
+	}
+}
+
+func TestLoadAlertRulesWindow(t *testing.T) {
+	// Test Window can be given as a duration string or in nanoseconds.
+	//
+	var s State
+	s.Info("test", 1)
+
+	config := `[
+		{"Name": "slow", "Metric": "response_time", "Op": ">", "Threshold": 500, "Window": "5m"},
+		{"Name": "slower", "Metric": "response_time", "Op": ">", "Threshold": 900, "Window": 60000000000}
+	]`
+	if err := s.LoadAlertRules(strings.NewReader(config)); err != nil {
+		t.Fatalf("LoadAlertRules returned error: %s", err)
+	}
+
+	if s.alerts["slow"].rule.Window != 5*time.Minute {
+		t.Errorf("Window expected 5m, got: %s", s.alerts["slow"].rule.Window)
+	}
+
+	if s.alerts["slower"].rule.Window != time.Minute {
+		t.Errorf("Window expected 1m, got: %s", s.alerts["slower"].rule.Window)
+	}
+
+	config = `[{"Name": "bad", "Metric": "response_time", "Op": ">", "Threshold": 1, "Window": "five minutes"}]`
+	if err := s.LoadAlertRules(strings.NewReader(config)); err == nil {
+		t.Errorf("LoadAlertRules accepted an invalid Window")
+	}
+}
@@ -0,0 +1,56 @@
+package health
+
+// Cardinality reports how many unique metric names are held, so runaway
+// names (user IDs in metric names, for example) can be spotted.
+type Cardinality struct {
+	Metrics        int
+	Gauges         int
+	RollingMetrics int
+	MaxMetrics     int // 0 means no limit
+	Dropped        int
+}
+
+// SetMaxMetrics caps the number of unique names held for each metric type
+// (counters, gauges and rolling metrics). Once a type is at the cap, new
+// names are dropped and counted in HealthInternal dropped_metrics, while
+// existing metrics keep updating. A max of 0, the default, means no limit.
+func (s *State) SetMaxMetrics(max int) {
+
+	if max < 0 {
+		s.strictf("health: SetMaxMetrics called with %d", max)
+		return
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.maxMetrics = max
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// Cardinality returns the current number of unique metric names.
+func (s *State) Cardinality() Cardinality {
+
+	mu.Lock() // enter CRITICAL SECTION
+	defer mu.Unlock()
+
+	return Cardinality{
+		Metrics:        len(s.Metrics),
+		Gauges:         len(s.Gauges),
+		RollingMetrics: len(s.RollingMetrics),
+		MaxMetrics:     s.maxMetrics,
+		Dropped:        int(s.HealthInternal[internalDroppedMetrics]),
+	}
+}
+
+// allowNewMetric reports whether a new name can be added to a metric type
+// that already holds count names, counting the drop if not. Must be called
+// from within the critical section.
+func (s *State) allowNewMetric(count int) bool {
+
+	if s.maxMetrics == 0 || count < s.maxMetrics {
+		return true
+	}
+
+	s.incrInternal(internalDroppedMetrics, 1)
+
+	return false
+}
@@ -0,0 +1,65 @@
+package health
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSetMaxMetrics(t *testing.T) {
+	// Test new names past the cap are dropped and counted, while existing
+	// metrics keep updating.
+	var s State
+	s.Info("test", 10)
+	s.SetMaxMetrics(2)
+
+	for i := 0; i < 5; i++ {
+		s.SetGauge("user-"+strconv.Itoa(i), 1)
+		s.UpdateRollingMetric("latency-"+strconv.Itoa(i), 1)
+	}
+	s.SetGauge("user-0", 2)
+
+	c := s.Cardinality()
+	if c.Gauges != 2 || c.RollingMetrics != 2 {
+		t.Errorf("Cardinality expected 2 gauges and 2 rolling metrics, got: %+v", c)
+	}
+
+	if c.Dropped != 6 {
+		t.Errorf("Cardinality expected 6 dropped, got: %d", c.Dropped)
+	}
+
+	if s.Gauges["user-0"] != 2 {
+		t.Errorf("Existing gauge failed to update at the cap")
+	}
+}
+
+func TestSetMaxMetricsCounters(t *testing.T) {
+	// Test the cap applies to counters, with drops counted internally
+	// rather than as another counter.
+	var s State
+	s.Info("test", 10)
+	s.SetMaxMetrics(1)
+
+	s.IncrMetric("requests")
+	s.IncrMetric("user-1")
+	s.IncrMetric("user-2")
+
+	if s.Metrics["requests"] != 1 || s.HealthInternal[internalDroppedMetrics] != 2 || len(s.Metrics) != 1 {
+		t.Errorf("Counter cap failed, got: %v", s.Metrics)
+	}
+}
+
+func TestCardinalityUnlimited(t *testing.T) {
+	// Test there is no limit by default.
+	//
+	var s State
+	s.Info("test", 10)
+
+	for i := 0; i < 100; i++ {
+		s.IncrMetric("user-" + strconv.Itoa(i))
+	}
+
+	c := s.Cardinality()
+	if c.Metrics != 100 || c.Dropped != 0 || c.MaxMetrics != 0 {
+		t.Errorf("Cardinality expected 100 metrics and no drops, got: %+v", c)
+	}
+}
@@ -0,0 +1,324 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// defaultCheckTimeout is used when a check is registered without a timeout.
+const defaultCheckTimeout = 5 * time.Second
+
+// checkHistoryPeriod is how far back check runs are kept for flakiness
+// stats, and maxCheckHistory caps how many runs are kept per check.
+const (
+	checkHistoryPeriod = 24 * time.Hour
+	maxCheckHistory    = 10000
+)
+
+// CheckFunc is a readiness or liveness check. It returns nil when healthy.
+type CheckFunc func(ctx context.Context) error
+
+// CheckOptions tune how a registered check is run.
+type CheckOptions struct {
+	Timeout   time.Duration // how long the check may run, default 5s
+	CacheFor  time.Duration // reuse the last result for this long, default 0
+	Optional  bool          // an optional check failing does not fail the status
+	DependsOn []string      // checks whose failure suppresses this one's
+}
+
+// CheckResult is the outcome of the most recent run of a check, with
+// flakiness stats over the runs of the last 24 hours. A failing check is
+// Suppressed when a check it depends on is also failing.
+type CheckResult struct {
+	Name           string
+	Healthy        bool
+	Optional       bool
+	LatencyMs      float64
+	LastError      string
+	CheckedAt      int64
+	Runs24h        int
+	Failures24h    int
+	FailureRate24h float64
+	Suppressed     bool
+}
+
+// Status is the JSON body written by StatusHandler.
+type Status struct {
+	Identity    string
+	Healthy     bool
+	Maintenance string
+	Checks      []CheckResult
+}
+
+type check struct {
+	run     CheckFunc
+	options CheckOptions
+	result  CheckResult
+	ranAt   time.Time
+	history []checkRun
+}
+
+type checkRun struct {
+	at      time.Time
+	healthy bool
+}
+
+// RegisterCheck adds a named critical check with the default options. A
+// check registered with the same name as an existing check replaces it.
+func (s *State) RegisterCheck(name string, run CheckFunc) {
+	s.RegisterCheckWithOptions(name, run, CheckOptions{})
+}
+
+// RegisterCheckWithOptions adds a named check with its own timeout,
+// caching and criticality.
+func (s *State) RegisterCheckWithOptions(name string, run CheckFunc, options CheckOptions) {
+
+	if len(name) < 1 || run == nil { // no name, no entry
+		s.strictf("health: RegisterCheck called without a name or check function")
+		return
+	}
+
+	if options.Timeout <= 0 {
+		options.Timeout = defaultCheckTimeout
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	if s.checks == nil {
+		s.checks = make(map[string]*check)
+	}
+
+	s.checks[name] = &check{run: run, options: options}
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// RunChecks runs every registered check concurrently, and returns the
+// results sorted by name. The returned bool is false if any critical
+// check failed.
+func (s *State) RunChecks(ctx context.Context) ([]CheckResult, bool) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	names := make([]string, 0, len(s.checks))
+	toRun := make(map[string]*check)
+	for name, c := range s.checks {
+		names = append(names, name)
+		if c.options.CacheFor <= 0 || time.Since(c.ranAt) > c.options.CacheFor {
+			toRun[name] = c
+		}
+	}
+	mu.Unlock() // end CRITICAL SECTION
+
+	sort.Strings(names)
+
+	type ran struct {
+		c      *check
+		result CheckResult
+	}
+	done := make(chan ran, len(toRun))
+	for name, c := range toRun {
+		go func(name string, c *check) {
+			done <- ran{c, runCheck(ctx, name, c.run, c.options)}
+		}(name, c)
+	}
+
+	ranAt := time.Now()
+	finished := make([]ran, 0, len(toRun))
+	for i := 0; i < len(toRun); i++ {
+		finished = append(finished, <-done)
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	for i, r := range finished {
+		r.c.ranAt = ranAt
+		r.c.record(ranAt, r.result.Healthy)
+		r.c.result = r.result
+		r.c.result.Runs24h, r.c.result.Failures24h, r.c.result.FailureRate24h = r.c.flakiness()
+		finished[i].result = r.c.result
+	}
+
+	results := make([]CheckResult, 0, len(names))
+	for _, name := range names {
+		if c, ok := s.checks[name]; ok { // may be removed while we were running
+			results = append(results, c.result)
+		}
+	}
+
+	suppressed := s.suppressedChecks(results)
+	healthy := true
+	for i := range results {
+		results[i].Suppressed = suppressed[results[i].Name]
+		if !results[i].Healthy && !results[i].Optional {
+			healthy = false
+		}
+	}
+	mu.Unlock() // end CRITICAL SECTION
+
+	for _, r := range finished {
+		outcome := "ok"
+		if !r.result.Healthy {
+			outcome = "failed"
+		}
+		s.IncrMetricWithTags("check_runs", map[string]string{"check": r.result.Name, "result": outcome})
+		s.UpdateRollingMetricWithTags("check_latency_ms", map[string]string{"check": r.result.Name}, r.result.LatencyMs)
+
+		if !r.result.Healthy && !suppressed[r.result.Name] {
+			s.publish(EventInfo{
+				Event:  EventCheckFailed,
+				Name:   r.result.Name,
+				Value:  r.result.LatencyMs,
+				Detail: r.result.LastError,
+				Time:   r.result.CheckedAt,
+			})
+		}
+	}
+
+	return results, healthy
+}
+
+// suppressedChecks returns the failing checks that depend on another
+// failing check, so only the upstream failure is published as an event.
+// Suppressed checks still count towards the overall status. Checks that
+// depend on each other in a cycle don't suppress each other, so a cycle
+// can't hide every failure. Must be called from within the critical section.
+func (s *State) suppressedChecks(results []CheckResult) map[string]bool {
+
+	failing := make(map[string]bool)
+	for _, r := range results {
+		if !r.Healthy {
+			failing[r.Name] = true
+		}
+	}
+
+	suppressed := make(map[string]bool)
+	for name := range failing {
+		for _, dep := range s.checks[name].options.DependsOn {
+			if failing[dep] && !s.dependsOn(dep, name, map[string]bool{}) {
+				suppressed[name] = true
+				break
+			}
+		}
+	}
+	return suppressed
+}
+
+// dependsOn reports whether check from depends on check to, directly or
+// through other checks. Must be called from within the critical section.
+func (s *State) dependsOn(from, to string, visited map[string]bool) bool {
+
+	if visited[from] {
+		return false
+	}
+	visited[from] = true
+
+	c, ok := s.checks[from]
+	if !ok {
+		return false
+	}
+
+	for _, dep := range c.options.DependsOn {
+		if dep == to || s.dependsOn(dep, to, visited) {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds a run to the check's history, dropping runs older than the
+// history period. Must be called from within the critical section.
+func (c *check) record(at time.Time, healthy bool) {
+
+	cutoff := at.Add(-checkHistoryPeriod)
+	keep := 0
+	for keep < len(c.history) && c.history[keep].at.Before(cutoff) {
+		keep++
+	}
+	c.history = c.history[keep:]
+
+	if len(c.history) >= maxCheckHistory {
+		c.history = c.history[1:]
+	}
+	c.history = append(c.history, checkRun{at: at, healthy: healthy})
+}
+
+// flakiness returns the runs, failures and failure rate over the history.
+// Must be called from within the critical section.
+func (c *check) flakiness() (int, int, float64) {
+
+	failures := 0
+	for _, run := range c.history {
+		if !run.healthy {
+			failures++
+		}
+	}
+
+	if len(c.history) == 0 {
+		return 0, 0, 0
+	}
+	return len(c.history), failures, float64(failures) / float64(len(c.history))
+}
+
+// runCheck runs a single check, enforcing its timeout even when the check
+// ignores its context.
+func runCheck(ctx context.Context, name string, run CheckFunc, options CheckOptions) CheckResult {
+
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	defer cancel()
+
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() {
+		errc <- run(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = errors.New("check timed out")
+	}
+
+	result := CheckResult{
+		Name:      name,
+		Healthy:   err == nil,
+		Optional:  options.Optional,
+		LatencyMs: float64(time.Since(start)) / float64(time.Millisecond),
+		CheckedAt: start.Unix(),
+	}
+	if err != nil {
+		result.LastError = err.Error()
+	}
+	return result
+}
+
+// StatusHandler runs the registered checks and writes a JSON Status. It
+// responds with 503 Service Unavailable when any critical check fails, and
+// with the maintenance status code while in maintenance.
+func (s *State) StatusHandler(w http.ResponseWriter, r *http.Request) {
+
+	results, healthy := s.RunChecks(r.Context())
+
+	mu.Lock() // enter CRITICAL SECTION
+	status := Status{
+		Identity:    s.Identity,
+		Healthy:     healthy,
+		Maintenance: s.Maintenance,
+		Checks:      results,
+	}
+	maintenanceCode := s.maintenanceCode
+	mu.Unlock() // end CRITICAL SECTION
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case len(status.Maintenance) > 0:
+		if maintenanceCode == 0 {
+			maintenanceCode = defaultMaintenanceCode
+		}
+		w.WriteHeader(maintenanceCode)
+	case !healthy:
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,223 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStatusHandlerHealthy(t *testing.T) {
+	// Test the status handler returns 200 when all checks pass.
+	//
+	var s State
+	s.Info("test", 10)
+	s.RegisterCheck("db", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("StatusHandler expected 200, got: %d", rec.Code)
+	}
+
+	searchFor := "\"Name\":\"db\",\"Healthy\":true"
+	if strings.Index(rec.Body.String(), searchFor) < 0 {
+		t.Errorf("StatusHandler body missing check result: %s", rec.Body.String())
+	}
+}
+
+func TestStatusHandlerWithoutInfo(t *testing.T) {
+	// Test checks run on a State that Info was never called on, and
+	// metrics can still be recorded afterwards.
+	var s State
+	s.RegisterCheck("db", func(ctx context.Context) error { return nil })
+
+	rec := httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("StatusHandler expected 200, got: %d", rec.Code)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.IncrMetric("myMetric")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Recording a metric after StatusHandler deadlocked")
+	}
+}
+
+func TestStatusHandlerCriticalFailure(t *testing.T) {
+	// Test a failing critical check returns 503 with the error, while a
+	// failing optional check does not.
+	var s State
+	s.Info("test", 10)
+	s.RegisterCheckWithOptions("cache", func(ctx context.Context) error {
+		return errors.New("cache down")
+	}, CheckOptions{Optional: true})
+
+	rec := httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Optional check failure expected 200, got: %d", rec.Code)
+	}
+
+	s.RegisterCheck("db", func(ctx context.Context) error {
+		return errors.New("connection refused")
+	})
+
+	rec = httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Critical check failure expected 503, got: %d", rec.Code)
+	}
+
+	searchFor := "\"LastError\":\"connection refused\""
+	if strings.Index(rec.Body.String(), searchFor) < 0 {
+		t.Errorf("StatusHandler body missing check error")
+	}
+}
+
+func TestCheckTimeout(t *testing.T) {
+	// Test a check that ignores its context still times out.
+	//
+	var s State
+	s.Info("test", 10)
+	s.RegisterCheckWithOptions("slow", func(ctx context.Context) error {
+		time.Sleep(200 * time.Millisecond)
+		return nil
+	}, CheckOptions{Timeout: 10 * time.Millisecond})
+
+	results, healthy := s.RunChecks(context.Background())
+	if healthy || results[0].LastError != "check timed out" {
+		t.Errorf("Slow check failed to time out")
+	}
+}
+
+func TestCheckCaching(t *testing.T) {
+	// Test a cached check is not re-run within its cache period.
+	//
+	runs := 0
+
+	var s State
+	s.Info("test", 10)
+	s.RegisterCheckWithOptions("db", func(ctx context.Context) error {
+		runs++
+		return nil
+	}, CheckOptions{CacheFor: time.Minute})
+
+	s.RunChecks(context.Background())
+	s.RunChecks(context.Background())
+
+	if runs != 1 {
+		t.Errorf("Cached check expected 1 run, got: %d", runs)
+	}
+}
+
+func TestCheckFlakiness(t *testing.T) {
+	// Test runs are recorded as metrics, and flakiness is reported over
+	// the history of runs.
+	fail := false
+
+	var s State
+	s.Info("test", 10)
+	s.RegisterCheck("db", func(ctx context.Context) error {
+		if fail {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+
+	for i := 0; i < 4; i++ {
+		fail = i == 3
+		s.RunChecks(context.Background())
+	}
+
+	fail = false
+	results, _ := s.RunChecks(context.Background())
+	r := results[0]
+	if r.Runs24h != 5 || r.Failures24h != 1 || r.FailureRate24h != 0.2 {
+		t.Errorf("Check flakiness expected 5 runs, 1 failure, got: %+v", r)
+	}
+
+	if s.Metrics[`check_runs{check="db",result="ok"}`] != 4 || s.Metrics[`check_runs{check="db",result="failed"}`] != 1 {
+		t.Errorf("Check runs not recorded as metrics, got: %v", s.Metrics)
+	}
+
+	if _, ok := s.RollingMetrics[`check_latency_ms{check="db"}`]; !ok {
+		t.Errorf("Check latency not recorded")
+	}
+}
+
+func TestCheckHistoryExpires(t *testing.T) {
+	// Test runs older than the history period are dropped.
+	//
+	var c check
+	now := time.Now()
+
+	c.record(now.Add(-25*time.Hour), false)
+	c.record(now, true)
+
+	runs, failures, _ := c.flakiness()
+	if runs != 1 || failures != 0 {
+		t.Errorf("Check history expected 1 run and no failures, got: %d runs, %d failures", runs, failures)
+	}
+}
+
+func TestCheckDependencySuppression(t *testing.T) {
+	// Test a failing check is suppressed when the check it depends on is
+	// failing too, directly or through a chain, and only the upstream
+	// failure is published.
+	var published []string
+
+	var s State
+	s.Info("test", 10)
+	s.Subscribe(EventCheckFailed, func(info EventInfo) { published = append(published, info.Name) })
+
+	down := func(ctx context.Context) error { return errors.New("down") }
+	s.RegisterCheckWithOptions("network", down, CheckOptions{Optional: true})
+	s.RegisterCheckWithOptions("db", down, CheckOptions{DependsOn: []string{"network"}})
+	s.RegisterCheckWithOptions("orders", down, CheckOptions{DependsOn: []string{"db"}})
+
+	results, healthy := s.RunChecks(context.Background())
+
+	for _, r := range results {
+		if r.Suppressed != (r.Name != "network") {
+			t.Errorf("Check %s has wrong suppression: %+v", r.Name, r)
+		}
+	}
+
+	if healthy {
+		t.Errorf("Suppressed critical checks should still fail the status")
+	}
+
+	if len(published) != 1 || published[0] != "network" {
+		t.Errorf("Expected only the network failure published, got: %v", published)
+	}
+}
+
+func TestCheckDependencyCycle(t *testing.T) {
+	// Test checks in a dependency cycle don't suppress each other.
+	//
+	var s State
+	s.Info("test", 10)
+
+	down := func(ctx context.Context) error { return errors.New("down") }
+	s.RegisterCheckWithOptions("a", down, CheckOptions{DependsOn: []string{"b"}})
+	s.RegisterCheckWithOptions("b", down, CheckOptions{DependsOn: []string{"a"}})
+
+	results, healthy := s.RunChecks(context.Background())
+
+	if healthy || results[0].Suppressed || results[1].Suppressed {
+		t.Errorf("Checks in a cycle should not be suppressed, got: %+v", results)
+	}
+}
@@ -0,0 +1,254 @@
+// Command healthbench drives metric updates against an in-process State and
+// reports throughput, latency percentiles and memory use, so sizing can be
+// checked on real hardware before a production rollout.
+//
+// Usage:
+//
+//	healthbench -rate 50000 -duration 30s -workers 8 -metrics 1000 -kind mixed
+//
+// A rate of 0 runs the workers flat out.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/thisdougb/health"
+)
+
+// reservoirSize is how many latencies each worker keeps for percentiles,
+// so the benchmark's own memory stays bounded however long it runs.
+const reservoirSize = 10000
+
+// config holds the benchmark settings, one field per flag.
+type config struct {
+	Rate     int           // target updates per second across all workers, 0 for unlimited
+	Duration time.Duration // how long to run
+	Workers  int           // concurrent goroutines recording metrics
+	Metrics  int           // distinct metric names
+	Kind     string        // counter, gauge, rolling or mixed
+	Window   int           // rolling data size passed to State.Info
+}
+
+// result is what a benchmark run measured.
+type result struct {
+	Ops        int
+	Elapsed    time.Duration
+	P50        time.Duration
+	P95        time.Duration
+	P99        time.Duration
+	Max        time.Duration
+	HeapBefore uint64
+	HeapAfter  uint64
+	DumpBytes  int
+}
+
+func main() {
+
+	var cfg config
+	flag.IntVar(&cfg.Rate, "rate", 0, "target updates per second, 0 for unlimited")
+	flag.DurationVar(&cfg.Duration, "duration", 10*time.Second, "how long to run")
+	flag.IntVar(&cfg.Workers, "workers", runtime.NumCPU(), "concurrent workers")
+	flag.IntVar(&cfg.Metrics, "metrics", 100, "distinct metric names")
+	flag.StringVar(&cfg.Kind, "kind", "mixed", "metric kind: counter, gauge, rolling or mixed")
+	flag.IntVar(&cfg.Window, "window", 100, "rolling data size")
+	flag.Parse()
+
+	if err := cfg.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "healthbench:", err)
+		os.Exit(2)
+	}
+
+	report(run(cfg))
+}
+
+// validate checks the flags make sense before a run starts.
+func (c config) validate() error {
+
+	switch {
+	case c.Rate < 0:
+		return fmt.Errorf("rate must not be negative, got %d", c.Rate)
+	case c.Duration <= 0:
+		return fmt.Errorf("duration must be positive, got %s", c.Duration)
+	case c.Workers < 1:
+		return fmt.Errorf("workers must be at least 1, got %d", c.Workers)
+	case c.Metrics < 1:
+		return fmt.Errorf("metrics must be at least 1, got %d", c.Metrics)
+	case c.Window < 1:
+		return fmt.Errorf("window must be at least 1, got %d", c.Window)
+	}
+
+	switch c.Kind {
+	case "counter", "gauge", "rolling", "mixed":
+		return nil
+	}
+	return fmt.Errorf("unknown kind %q", c.Kind)
+}
+
+// run records metrics for cfg.Duration and measures each update.
+func run(cfg config) result {
+
+	var s health.State
+	s.Info("healthbench", cfg.Window)
+
+	names := make([]string, cfg.Metrics)
+	for i := range names {
+		names[i] = "bench_" + strconv.Itoa(i)
+	}
+
+	// each worker paces itself to an equal share of the target rate
+	var interval time.Duration
+	if cfg.Rate > 0 {
+		interval = time.Duration(int64(time.Second) * int64(cfg.Workers) / int64(cfg.Rate))
+	}
+
+	var before runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	samples := make([]*reservoir, cfg.Workers)
+	start := time.Now()
+	deadline := start.Add(cfg.Duration)
+
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Workers; w++ {
+		samples[w] = newReservoir(reservoirSize, int64(w))
+		wg.Add(1)
+		go func(w int, res *reservoir) {
+			defer wg.Done()
+
+			for i := 0; ; i++ {
+				now := time.Now()
+				if !now.Before(deadline) {
+					break
+				}
+				if interval > 0 {
+					if next := start.Add(time.Duration(i) * interval); next.After(now) {
+						time.Sleep(next.Sub(now))
+					}
+				}
+
+				name := names[(w+i*cfg.Workers)%len(names)]
+				t := time.Now()
+				record(&s, cfg.Kind, name, i)
+				res.add(time.Since(t))
+			}
+		}(w, samples[w])
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	r := result{Elapsed: elapsed}
+	var all []time.Duration
+	for _, res := range samples {
+		r.Ops += res.seen
+		if res.max > r.Max {
+			r.Max = res.max
+		}
+		all = append(all, res.values...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	r.P50 = percentile(all, 50)
+	r.P95 = percentile(all, 95)
+	r.P99 = percentile(all, 99)
+
+	// drop the latency samples, so the heap reading is the State
+	samples, all = nil, nil
+
+	var after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	r.HeapBefore = before.HeapAlloc
+	r.HeapAfter = after.HeapAlloc
+	r.DumpBytes = len(s.Dump())
+	return r
+}
+
+// reservoir keeps a uniform random sample of a worker's latencies, along
+// with the count and maximum of all of them.
+type reservoir struct {
+	values []time.Duration
+	seen   int
+	max    time.Duration
+	rng    *rand.Rand
+}
+
+func newReservoir(size int, seed int64) *reservoir {
+	return &reservoir{
+		values: make([]time.Duration, 0, size),
+		rng:    rand.New(rand.NewSource(seed)),
+	}
+}
+
+// add records one latency, replacing a random sample once the reservoir
+// is full.
+func (r *reservoir) add(d time.Duration) {
+
+	r.seen++
+	if d > r.max {
+		r.max = d
+	}
+
+	if len(r.values) < cap(r.values) {
+		r.values = append(r.values, d)
+		return
+	}
+	if i := r.rng.Intn(r.seen); i < len(r.values) {
+		r.values[i] = d
+	}
+}
+
+// record makes one metric update of the given kind.
+func record(s *health.State, kind, name string, i int) {
+
+	if kind == "mixed" {
+		kind = [...]string{"counter", "gauge", "rolling"}[i%3]
+	}
+
+	switch kind {
+	case "counter":
+		s.IncrMetric(name)
+	case "gauge":
+		s.SetGauge(name, float64(i))
+	case "rolling":
+		s.UpdateRollingMetric(name, float64(i%1000))
+	}
+}
+
+// percentile returns the nearest-rank percentile p of sorted latencies, the
+// same way the health package reports RollingPercentiles.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+func report(r result) {
+
+	fmt.Printf("ops:         %d\n", r.Ops)
+	fmt.Printf("elapsed:     %s\n", r.Elapsed.Round(time.Millisecond))
+	fmt.Printf("throughput:  %.0f ops/s\n", float64(r.Ops)/r.Elapsed.Seconds())
+	fmt.Printf("latency:     p50 %s  p95 %s  p99 %s  max %s\n", r.P50, r.P95, r.P99, r.Max)
+	fmt.Printf("heap:        %d KiB before, %d KiB after\n", r.HeapBefore/1024, r.HeapAfter/1024)
+	fmt.Printf("dump size:   %d bytes\n", r.DumpBytes)
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	// Test a short run records updates and measures their latency.
+	//
+	cfg := config{Duration: 50 * time.Millisecond, Workers: 2, Metrics: 10, Kind: "mixed", Window: 10}
+
+	r := run(cfg)
+
+	if r.Ops < 1 {
+		t.Errorf("run recorded no updates")
+	}
+
+	if r.P50 > r.P99 || r.P99 > r.Max {
+		t.Errorf("run latencies out of order, got p50 %s p99 %s max %s", r.P50, r.P99, r.Max)
+	}
+
+	if r.DumpBytes < 1 {
+		t.Errorf("run failed to report dump size")
+	}
+}
+
+func TestRunRate(t *testing.T) {
+	// Test a target rate limits the number of updates.
+	//
+	cfg := config{Rate: 100, Duration: 100 * time.Millisecond, Workers: 2, Metrics: 10, Kind: "counter", Window: 10}
+
+	r := run(cfg)
+
+	if r.Ops > 20 {
+		t.Errorf("run exceeded target rate, expected about 10 updates, got: %d", r.Ops)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// Test bad settings are rejected before a run.
+	//
+	good := config{Duration: time.Second, Workers: 1, Metrics: 1, Kind: "counter", Window: 1}
+	if err := good.validate(); err != nil {
+		t.Errorf("validate rejected good config: %s", err)
+	}
+
+	bad := good
+	bad.Kind = "histogram"
+	if err := bad.validate(); err == nil {
+		t.Errorf("validate accepted unknown kind")
+	}
+
+	bad = good
+	bad.Workers = 0
+	if err := bad.validate(); err == nil {
+		t.Errorf("validate accepted zero workers")
+	}
+}
+
+func TestReservoir(t *testing.T) {
+	// Test the reservoir stays bounded while counting every latency.
+	//
+	res := newReservoir(10, 1)
+
+	for i := 1; i <= 100; i++ {
+		res.add(time.Duration(i))
+	}
+
+	if len(res.values) != 10 {
+		t.Errorf("reservoir expected 10 samples, got: %d", len(res.values))
+	}
+
+	if res.seen != 100 || res.max != 100 {
+		t.Errorf("reservoir expected 100 seen and max 100, got: %d and %d", res.seen, res.max)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	// Test nearest-rank percentiles of sorted latencies.
+	//
+	sorted := []time.Duration{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+	if got := percentile(sorted, 50); got != 5 {
+		t.Errorf("percentile 50 expected 5, got: %d", got)
+	}
+
+	if got := percentile(sorted, 21); got != 3 {
+		t.Errorf("percentile 21 expected 3, got: %d", got)
+	}
+
+	if got := percentile(sorted, 99); got != 10 {
+		t.Errorf("percentile 99 expected 10, got: %d", got)
+	}
+
+	if got := percentile(nil, 50); got != 0 {
+		t.Errorf("percentile of no latencies expected 0, got: %d", got)
+	}
+}
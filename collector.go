@@ -0,0 +1,123 @@
+package health
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// maxPushSize limits the size of a pushed payload.
+const maxPushSize = 10 << 20
+
+// Collector defaults, used when the matching field is left at zero.
+const (
+	defaultMaxIdentities = 1000
+	defaultStaleAfter    = 5 * defaultPushInterval
+)
+
+// Collector is an http.Handler that receives State snapshots sent by a
+// Pusher, and registers each one in a Registry under its identity. The
+// registry's Dump() then serves every pushing instance from one place.
+//
+// Pushes are rejected unless Token is set, or Insecure is set because the
+// handler sits behind other authentication. Each identity adds a State to
+// the registry, so the number of identities is capped, and identities that
+// stop pushing are removed after StaleAfter. StaleAfter must be longer than
+// the pushers' Interval plus Jitter, or an identity is removed between
+// pushes and flaps in the registry's Dump(). The 5m default suits pushers
+// at the default 1m Interval.
+type Collector struct {
+	Registry      *Registry
+	Token         string        // bearer token pushers must send
+	Insecure      bool          // accept pushes without a Token, only behind other auth
+	MaxIdentities int           // identities held at once, default 1000
+	StaleAfter    time.Duration // remove identities not pushed for this long, above Interval + Jitter, default 5m
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewCollector returns a Collector that stores pushed states in r.
+func NewCollector(r *Registry) *Collector {
+	return &Collector{Registry: r}
+}
+
+// ServeHTTP accepts a POSTed State snapshot.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if len(c.Token) < 1 && !c.Insecure {
+		http.Error(w, "collector has no Token set", http.StatusUnauthorized)
+		return
+	}
+
+	if len(c.Token) > 0 {
+		auth := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(auth, []byte("Bearer "+c.Token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var s State
+	err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxPushSize)).Decode(&s)
+	if err != nil {
+		http.Error(w, "invalid payload: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if len(s.Identity) < 1 {
+		http.Error(w, "payload has no Identity", http.StatusBadRequest)
+		return
+	}
+
+	c.Prune()
+
+	maxIdentities := c.MaxIdentities
+	if maxIdentities <= 0 {
+		maxIdentities = defaultMaxIdentities
+	}
+
+	c.mu.Lock()
+	if c.seen == nil {
+		c.seen = make(map[string]time.Time)
+	}
+	if _, exists := c.seen[s.Identity]; !exists && len(c.seen) >= maxIdentities {
+		c.mu.Unlock()
+		http.Error(w, "too many identities", http.StatusForbidden)
+		return
+	}
+	c.seen[s.Identity] = time.Now()
+	c.Registry.Register(s.Identity, &s)
+	c.mu.Unlock()
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Prune removes identities that have not pushed within StaleAfter from the
+// registry. It runs on every push, and can be called before serving the
+// registry's Dump() in case every instance has stopped pushing.
+func (c *Collector) Prune() {
+
+	staleAfter := c.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = defaultStaleAfter
+	}
+	cutoff := time.Now().Add(-staleAfter)
+
+	c.mu.Lock()
+	for identity, last := range c.seen {
+		if last.Before(cutoff) {
+			delete(c.seen, identity)
+			c.Registry.Unregister(identity)
+		}
+	}
+	c.mu.Unlock()
+}
@@ -0,0 +1,133 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCollectorReceivesPush(t *testing.T) {
+	// Test a Pusher's snapshot ends up in the collector's registry under
+	// the pushing instance's identity.
+	r := NewRegistry()
+	c := NewCollector(r)
+	c.Token = "secret"
+
+	server := httptest.NewServer(c)
+	defer server.Close()
+
+	var s State
+	s.Info("worker-1", 10)
+	s.IncrMetric("myMetric")
+
+	p := NewPusher(&s, server.URL)
+	p.Token = "secret"
+	if err := p.Push(); err != nil {
+		t.Fatalf("Push to collector returned error: %s", err)
+	}
+
+	received := r.Get("worker-1")
+	if received == nil || received.Metrics["myMetric"] != 1 {
+		t.Errorf("Collector failed to register pushed state")
+	}
+}
+
+func TestCollectorRejectsBadRequests(t *testing.T) {
+	// Test the collector validates method, token and payload.
+	//
+	c := NewCollector(NewRegistry())
+	c.Token = "secret"
+
+	tests := []struct {
+		method string
+		token  string
+		body   string
+		code   int
+	}{
+		{"GET", "secret", "", http.StatusMethodNotAllowed},
+		{"POST", "wrong", `{"Identity": "worker-1"}`, http.StatusUnauthorized},
+		{"POST", "secret", `not json`, http.StatusBadRequest},
+		{"POST", "secret", `{"Identity": ""}`, http.StatusBadRequest},
+	}
+
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/health/collect", strings.NewReader(test.body))
+		req.Header.Set("Authorization", "Bearer "+test.token)
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+
+		if rec.Code != test.code {
+			t.Errorf("Collector %s with %q expected %d, got: %d", test.method, test.body, test.code, rec.Code)
+		}
+	}
+}
+
+func TestCollectorRequiresToken(t *testing.T) {
+	// Test pushes are rejected when no Token is set, unless the collector
+	// is marked Insecure.
+	c := NewCollector(NewRegistry())
+
+	push := func() int {
+		req := httptest.NewRequest("POST", "/health/collect", strings.NewReader(`{"Identity": "worker-1"}`))
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := push(); code != http.StatusUnauthorized {
+		t.Errorf("Collector without Token expected %d, got: %d", http.StatusUnauthorized, code)
+	}
+
+	c.Insecure = true
+	if code := push(); code != http.StatusNoContent {
+		t.Errorf("Insecure collector expected %d, got: %d", http.StatusNoContent, code)
+	}
+}
+
+func TestCollectorLimitsIdentities(t *testing.T) {
+	// Test new identities beyond MaxIdentities are rejected, while known
+	// identities can still push.
+	c := NewCollector(NewRegistry())
+	c.Insecure = true
+	c.MaxIdentities = 1
+
+	push := func(identity string) int {
+		req := httptest.NewRequest("POST", "/health/collect", strings.NewReader(`{"Identity": "`+identity+`"}`))
+		rec := httptest.NewRecorder()
+		c.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := push("worker-1"); code != http.StatusNoContent {
+		t.Errorf("Collector expected first identity accepted, got: %d", code)
+	}
+
+	if code := push("worker-2"); code != http.StatusForbidden {
+		t.Errorf("Collector expected identity over the limit rejected, got: %d", code)
+	}
+
+	if code := push("worker-1"); code != http.StatusNoContent {
+		t.Errorf("Collector expected known identity accepted, got: %d", code)
+	}
+}
+
+func TestCollectorPrunesStaleIdentities(t *testing.T) {
+	// Test identities that stop pushing are removed from the registry.
+	//
+	r := NewRegistry()
+	c := NewCollector(r)
+	c.Insecure = true
+	c.StaleAfter = 10 * time.Millisecond
+
+	req := httptest.NewRequest("POST", "/health/collect", strings.NewReader(`{"Identity": "worker-1"}`))
+	c.ServeHTTP(httptest.NewRecorder(), req)
+
+	time.Sleep(20 * time.Millisecond)
+	c.Prune()
+
+	if r.Get("worker-1") != nil {
+		t.Errorf("Collector failed to prune stale identity")
+	}
+}
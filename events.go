@@ -0,0 +1,66 @@
+package health
+
+import "time"
+
+// Event is the type of something that happened inside the package.
+type Event string
+
+// Events published to subscribers.
+const (
+	EventThresholdBreached  Event = "threshold_breached"
+	EventThresholdResolved  Event = "threshold_resolved"
+	EventCheckFailed        Event = "check_failed"
+	EventMaintenanceStarted Event = "maintenance_started"
+	EventMaintenanceEnded   Event = "maintenance_ended"
+	EventPushCompleted      Event = "push_completed"
+	EventPushFailed         Event = "push_failed"
+)
+
+// EventInfo describes a published event. Name is the alert rule, check or
+// push URL the event is about, and Detail holds an error or reason.
+type EventInfo struct {
+	Event  Event
+	Name   string
+	Value  float64
+	Detail string
+	Time   int64
+}
+
+// EventHandler is called with each event it is subscribed to. Handlers are
+// called synchronously from the goroutine that caused the event, so they
+// should return quickly and hand any slow work to another goroutine.
+type EventHandler func(info EventInfo)
+
+// Subscribe calls handler every time event is published, giving extensions
+// a single integration point for alerts, checks, maintenance and pushes.
+func (s *State) Subscribe(event Event, handler EventHandler) {
+
+	if handler == nil {
+		return
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	if s.subscribers == nil {
+		s.subscribers = make(map[Event][]EventHandler)
+	}
+
+	s.subscribers[event] = append(s.subscribers[event], handler)
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// publish calls the handlers subscribed to info.Event. Must be called from
+// outside the critical section, so handlers can record metrics.
+func (s *State) publish(info EventInfo) {
+
+	if info.Time == 0 {
+		info.Time = time.Now().Unix()
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	handlers := s.subscribers[info.Event]
+	mu.Unlock() // end CRITICAL SECTION
+
+	for _, handler := range handlers {
+		handler(info)
+	}
+}
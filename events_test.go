@@ -0,0 +1,66 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestSubscribeThresholdEvents(t *testing.T) {
+	// Test alert transitions are published to subscribers.
+	//
+	var got []EventInfo
+
+	var s State
+	s.Info("test", 1)
+	s.AddAlertRule(AlertRule{Name: "slow", Metric: "latency", Op: ">", Threshold: 500})
+	s.Subscribe(EventThresholdBreached, func(info EventInfo) { got = append(got, info) })
+	s.Subscribe(EventThresholdResolved, func(info EventInfo) { got = append(got, info) })
+
+	s.UpdateRollingMetric("latency", 600)
+	s.UpdateRollingMetric("latency", 100)
+
+	if len(got) != 2 || got[0].Event != EventThresholdBreached || got[1].Event != EventThresholdResolved {
+		t.Errorf("Expected breached then resolved events, got: %+v", got)
+	}
+
+	if got[0].Name != "slow" || got[0].Value != 600 {
+		t.Errorf("Threshold event has wrong details: %+v", got[0])
+	}
+}
+
+func TestSubscribeCheckFailed(t *testing.T) {
+	// Test failing checks are published, and handlers can record metrics.
+	//
+	var s State
+	s.Info("test", 10)
+	s.RegisterCheck("db", func(ctx context.Context) error { return errors.New("connection refused") })
+	s.Subscribe(EventCheckFailed, func(info EventInfo) {
+		s.IncrMetric("check_failed." + info.Name)
+	})
+
+	s.RunChecks(context.Background())
+
+	if s.Metrics["check_failed.db"] != 1 {
+		t.Errorf("Check failed event not published")
+	}
+}
+
+func TestSubscribeMaintenance(t *testing.T) {
+	// Test maintenance start and end are published once each.
+	//
+	var got []Event
+
+	var s State
+	s.Info("test", 10)
+	s.Subscribe(EventMaintenanceStarted, func(info EventInfo) { got = append(got, info.Event) })
+	s.Subscribe(EventMaintenanceEnded, func(info EventInfo) { got = append(got, info.Event) })
+
+	s.SetMaintenance("upgrade")
+	s.SetMaintenance("still upgrading")
+	s.SetMaintenance("")
+
+	if len(got) != 2 || got[0] != EventMaintenanceStarted || got[1] != EventMaintenanceEnded {
+		t.Errorf("Expected started then ended events, got: %v", got)
+	}
+}
@@ -2,7 +2,9 @@ package health
 
 import (
 	"encoding/json"
-	"log"
+	"math"
+	"os"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -14,13 +16,53 @@ type State struct {
 	Identity           string
 	Started            int64
 	RollingDataSize    int
+	Maintenance        string
 	Metrics            map[string]int
+	Gauges             map[string]float64
 	rollingMetricsData map[string]*rollingMetric
 	RollingMetrics     map[string]float64
+	RollingPercentiles map[string]map[string]float64
+	percentiles        []float64
+	EWMAMetrics        map[string]float64
+	ewmaAlpha          float64
+	HealthInternal     map[string]float64
+	scrubber           ScrubFunc
+	checks             map[string]*check
+	alerts             map[string]*alert
+	alertQueue         chan alertDelivery
+	alertDone          chan struct{}
+	shutdown           bool
+	maintenanceCode    int
+	logger             Logger
+	rawRetention       time.Duration
+	rawSamples         map[string][]Sample
+	maxMetrics         int
+	strict             bool
+	subscribers        map[Event][]EventHandler
+	apdexThreshold     time.Duration
 }
 
 var mu sync.Mutex // writer lock
 
+// maxMetricValue and minMetricValue are the limits a counter metric can
+// hold before it wraps around.
+const (
+	maxMetricValue = int(^uint(0) >> 1)
+	minMetricValue = -maxMetricValue - 1
+)
+
+// defaultPercentiles are reported for rolling metrics unless SetPercentiles
+// is called.
+var defaultPercentiles = []float64{50, 95, 99}
+
+// defaultRollingDataSize is the rolling data size used when Info is given
+// one below 1, or was never called.
+const defaultRollingDataSize = 10
+
+// defaultEWMAAlpha is the smoothing factor for EWMA metrics unless
+// SetEWMAAlpha is called.
+const defaultEWMAAlpha = 0.2
+
 // Info method sets the identity string for this metrics instance, and
 // the sample size of for rolling average metrics. The identity string
 // will be in the Dump() output. A unique ID means we can find
@@ -28,18 +70,23 @@ var mu sync.Mutex // writer lock
 func (s *State) Info(identity string, rollingDataSize int) {
 
 	defaultIdentity := "identity unset"
-	defaultRollingDataSize := 10
 
 	t := time.Now()
 	s.Started = t.Unix()
 
+	if strict, err := strconv.ParseBool(os.Getenv("HEALTH_STRICT")); err == nil {
+		s.SetStrict(strict)
+	}
+
 	if len(identity) == 0 {
+		s.strictf("health: Info called with an empty identity")
 		s.Identity = defaultIdentity
 	} else {
 		s.Identity = identity
 	}
 
 	if rollingDataSize < 1 {
+		s.strictf("health: Info called with rolling data size %d", rollingDataSize)
 		s.RollingDataSize = defaultRollingDataSize
 	} else {
 		s.RollingDataSize = rollingDataSize
@@ -49,35 +96,136 @@ func (s *State) Info(identity string, rollingDataSize int) {
 // IncrMetric increments a simple counter metric by one. Metrics start with a zero
 // value, so the very first call to IncrMetric() always results in a value of 1.
 func (s *State) IncrMetric(name string) {
+	s.IncrMetricBy(name, 1)
+}
+
+// DecrMetric decrements a simple counter metric by one, for counters that
+// track things like in-flight requests.
+func (s *State) DecrMetric(name string) {
+	s.IncrMetricBy(name, -1)
+}
+
+// IncrMetricBy adds n, which may be negative, to a simple counter metric.
+// A counter that would pass the maximum (or minimum) int value wraps around
+// through zero, rather than overflowing, so consumers see it as a counter
+// reset. Each wrap is logged and counted in HealthInternal as counter_wraps.
+func (s *State) IncrMetricBy(name string, n int) {
 
 	if len(name) < 1 { // no name, no entry
+		s.strictf("health: IncrMetricBy called with an empty metric name")
 		return
 	}
 
 	mu.Lock() // enter CRITICAL SECTION
+	name, ok := s.scrubName(name)
+	if !ok {
+		mu.Unlock()
+		return
+	}
+	if _, exists := s.Metrics[name]; !exists && !s.allowNewMetric(len(s.Metrics)) {
+		mu.Unlock()
+		return
+	}
 	if s.Metrics == nil {
 		s.Metrics = make(map[string]int)
 	}
 
-	s.Metrics[name]++
+	current := s.Metrics[name]
+	wrapped := true
+	switch {
+	case n > 0 && current > maxMetricValue-n:
+		s.Metrics[name] = n - (maxMetricValue - current) - 1
+	case n < 0 && current < minMetricValue-n:
+		s.Metrics[name] = n - (minMetricValue - current) + 1
+	default:
+		s.Metrics[name] = current + n
+		wrapped = false
+	}
+	if wrapped {
+		s.incrInternal(internalCounterWraps, 1)
+	}
+	mu.Unlock() // end CRITICAL SECTION
+
+	if wrapped {
+		s.logf("health: counter metric %s wrapped around through zero", name)
+	}
+}
+
+// SetGauge sets a gauge metric to value. A gauge holds the last observed
+// value, such as queue depth or open connections, rather than a count or
+// an average. NaN and infinite values are ignored, as they can't be output
+// as JSON.
+func (s *State) SetGauge(name string, value float64) {
+
+	if len(name) < 1 { // no name, no entry
+		s.strictf("health: SetGauge called with an empty metric name")
+		return
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		s.strictf("health: SetGauge %s called with %f, which can't be output as JSON", name, value)
+		return
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	name, ok := s.scrubName(name)
+	if !ok {
+		mu.Unlock()
+		return
+	}
+	if _, exists := s.Gauges[name]; !exists && !s.allowNewMetric(len(s.Gauges)) {
+		mu.Unlock()
+		return
+	}
+	if s.Gauges == nil {
+		s.Gauges = make(map[string]float64)
+	}
+
+	s.Gauges[name] = value
 	mu.Unlock() // end CRITICAL SECTION
 }
 
 // UpdateRollingMetric adds data point for this metric, and re-calculates the
 // rolling average metric value. Rolling averages are typical float types, so
-// we expect a float64 type as the data point parameter.
+// we expect a float64 type as the data point parameter. NaN and infinite
+// values are ignored, as they can't be output as JSON.
 func (s *State) UpdateRollingMetric(name string, value float64) {
+	s.updateRollingMetric(name, value, false)
+}
+
+// updateRollingMetric records a data point for UpdateRollingMetric. With
+// filledOnly, the average leaves out slots that have never been filled, for
+// scores where an empty slot must not count as zero.
+func (s *State) updateRollingMetric(name string, value float64, filledOnly bool) {
 
 	if len(name) < 1 { // no name, no entry
+		s.strictf("health: UpdateRollingMetric called with an empty metric name")
+		return
+	}
+
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		s.strictf("health: UpdateRollingMetric %s called with %f, which can't be output as JSON", name, value)
 		return
 	}
 
 	mu.Lock() // enter CRITICAL SECTION
-	_, ok := s.RollingMetrics[name]
+	name, ok := s.scrubName(name)
 	if !ok {
-		if s.RollingMetrics == nil {
+		mu.Unlock()
+		return
+	}
+	_, ok = s.rollingMetricsData[name]
+	if !ok {
+		if !s.allowNewMetric(len(s.RollingMetrics)) {
+			mu.Unlock()
+			return
+		}
+		if s.rollingMetricsData == nil {
 			s.rollingMetricsData = make(map[string]*rollingMetric)
 		}
+		if s.RollingDataSize < 1 { // Info not called
+			s.RollingDataSize = defaultRollingDataSize
+		}
 		var m rollingMetric
 		m.data = make([]float64, s.RollingDataSize)
 		s.rollingMetricsData[name] = &m
@@ -85,25 +233,119 @@ func (s *State) UpdateRollingMetric(name string, value float64) {
 
 	metric := s.rollingMetricsData[name]
 	newValue := metric.Add(value)
+	if filledOnly {
+		newValue = metric.Mean()
+	}
 
 	// update RollingMetrics for nicer json output
 	if s.RollingMetrics == nil {
 		s.RollingMetrics = make(map[string]float64)
 	}
 	s.RollingMetrics[name] = newValue
+
+	// exponentially weighted moving average, seeded with the first value
+	alpha := s.ewmaAlpha
+	if alpha == 0 {
+		alpha = defaultEWMAAlpha
+	}
+	if s.EWMAMetrics == nil {
+		s.EWMAMetrics = make(map[string]float64)
+	}
+	if ewma, ok := s.EWMAMetrics[name]; ok {
+		s.EWMAMetrics[name] = alpha*value + (1-alpha)*ewma
+	} else {
+		s.EWMAMetrics[name] = value
+	}
+
+	s.addRawSample(name, value)
+	mu.Unlock() // end CRITICAL SECTION
+
+	s.evaluateAlerts(name)
+}
+
+// SetEWMAAlpha sets the smoothing factor, between 0 and 1, used for the
+// EWMA of rolling metrics. A higher alpha follows new data points more
+// closely. Values outside the range are ignored.
+func (s *State) SetEWMAAlpha(alpha float64) {
+
+	if alpha <= 0 || alpha > 1 {
+		s.strictf("health: SetEWMAAlpha called with %f, outside 0 to 1", alpha)
+		return
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.ewmaAlpha = alpha
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// SetPercentiles sets which percentiles (0 to 100) are reported for rolling
+// metrics, replacing the default of p50, p95 and p99. Calling it with no
+// values turns percentile reporting off.
+func (s *State) SetPercentiles(percentiles ...float64) {
+
+	valid := []float64{}
+	for _, p := range percentiles {
+		if p >= 0 && p <= 100 {
+			valid = append(valid, p)
+		} else {
+			s.strictf("health: SetPercentiles called with %f, outside 0 to 100", p)
+		}
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.percentiles = valid
 	mu.Unlock() // end CRITICAL SECTION
 }
 
-// Dump returns a JSON byte-string.
-// We do not use a reader lock because it is probably unnecessary here,
-// in this scenario.
+// updatePercentiles refreshes RollingPercentiles from the rolling data. It
+// runs at Dump time rather than on every update, keeping sorting off the
+// write path. Must be called from within the critical section.
+func (s *State) updatePercentiles() {
+
+	percentiles := s.percentiles
+	if percentiles == nil {
+		percentiles = defaultPercentiles
+	}
+	if len(percentiles) == 0 {
+		if len(s.rollingMetricsData) > 0 { // turned off, drop stale values
+			s.RollingPercentiles = nil
+		}
+		return
+	}
+	if len(s.rollingMetricsData) == 0 {
+		return
+	}
+
+	if s.RollingPercentiles == nil {
+		s.RollingPercentiles = make(map[string]map[string]float64)
+	}
+	for name, metric := range s.rollingMetricsData {
+		values := make(map[string]float64, len(percentiles))
+		for i, v := range metric.Percentiles(percentiles...) {
+			values["p"+strconv.FormatFloat(percentiles[i], 'f', -1, 64)] = v
+		}
+		s.RollingPercentiles[name] = values
+	}
+}
+
+// Dump returns a JSON byte-string, or an empty JSON object if marshalling
+// fails.
+// Dump holds the writer lock while marshalling, because it can be called
+// from background goroutines (streaming, pushing) while metrics are written.
 func (s *State) Dump() string {
 
 	var dataString string
 
+	mu.Lock() // enter CRITICAL SECTION
+	if s.alertQueue != nil {
+		s.setInternal(internalAlertQueueDepth, float64(len(s.alertQueue)))
+	}
+	s.updatePercentiles()
 	data, err := json.MarshalIndent(s, "", "    ")
+	mu.Unlock() // end CRITICAL SECTION
 	if err != nil {
-		log.Fatalf("JSON Marshalling failed: %s", err)
+		s.logf("health: JSON Marshalling failed: %s", err)
+		return "{}"
 	}
 	dataString = string(data)
 
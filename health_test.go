@@ -1,6 +1,7 @@
 package health
 
 import (
+	"math"
 	"strconv"
 	"strings"
 	"testing"
@@ -106,6 +107,124 @@ func TestIncrMetricIgnoresEmptyName(t *testing.T) {
 		t.Errorf("Metric increment failed")
 	}
 }
+
+func TestIncrMetricWrapsAtMax(t *testing.T) {
+	// Test a counter at the max int value wraps to zero instead of
+	// overflowing to a negative value.
+	metricName := "myMetric"
+
+	var s State
+	s.Info("test", 10)
+	s.IncrMetric(metricName)
+	s.Metrics[metricName] = maxMetricValue
+
+	s.IncrMetric(metricName)
+	if s.Metrics[metricName] != 0 {
+		t.Errorf("Metric failed to wrap at max value, got: %d", s.Metrics[metricName])
+	}
+
+	if s.HealthInternal[internalCounterWraps] != 1 {
+		t.Errorf("Counter wrap not counted, got: %v", s.HealthInternal)
+	}
+
+	s.IncrMetric(metricName)
+	if s.Metrics[metricName] != 1 {
+		t.Errorf("Metric failed to increment after wrap, got: %d", s.Metrics[metricName])
+	}
+}
+
+func TestIncrMetricByAndDecrMetric(t *testing.T) {
+	// Test adding n to a counter, and decrementing it.
+	//
+	metricName := "inFlight"
+
+	var s State
+	s.Info("test", 10)
+
+	s.IncrMetricBy(metricName, 5)
+	s.DecrMetric(metricName)
+	s.IncrMetricBy(metricName, -2)
+
+	if s.Metrics[metricName] != 2 {
+		t.Errorf("IncrMetricBy/DecrMetric expected 2, got: %d", s.Metrics[metricName])
+	}
+}
+
+func TestIncrMetricByWrapsAtLimits(t *testing.T) {
+	// Test adding n past the max, or below the min, wraps through zero.
+	//
+	metricName := "myMetric"
+
+	var s State
+	s.Info("test", 10)
+	s.IncrMetric(metricName)
+
+	s.Metrics[metricName] = maxMetricValue - 1
+	s.IncrMetricBy(metricName, 3)
+	if s.Metrics[metricName] != 1 {
+		t.Errorf("IncrMetricBy failed to wrap at max value, got: %d", s.Metrics[metricName])
+	}
+
+	s.Metrics[metricName] = minMetricValue
+	s.DecrMetric(metricName)
+	if s.Metrics[metricName] != 0 {
+		t.Errorf("DecrMetric failed to wrap at min value, got: %d", s.Metrics[metricName])
+	}
+}
+
+func TestSetGauge(t *testing.T) {
+	// Test a gauge holds the last value set, not a count or average.
+	//
+	metricName := "queueDepth"
+
+	var s State
+	s.Info("test", 10)
+
+	s.SetGauge(metricName, 5)
+	s.SetGauge(metricName, 3)
+	result := s.Dump()
+
+	searchFor := "\"" + metricName + "\": 3"
+	searchResult := strings.Index(result, searchFor)
+	if searchResult < 0 {
+		t.Errorf("Gauge set failed")
+	}
+}
+
+func TestSetGaugeIgnoresEmptyName(t *testing.T) {
+	// Test setting a gauge when supplying no name string
+	// ignores the value.
+	var s State
+	s.Info("test", 10)
+
+	s.SetGauge("", 1)
+	result := s.Dump()
+	searchFor := "\"Gauges\": null"
+	searchResult := strings.Index(result, searchFor)
+	if searchResult < 0 {
+		t.Errorf("Gauge set with empty name was recorded")
+	}
+}
+
+func TestSetGaugeIgnoresNaN(t *testing.T) {
+	// Test a NaN or infinite gauge is not recorded, so Dump keeps working.
+	//
+	var s State
+	s.Info("test", 10)
+
+	s.SetGauge("myGauge", 2)
+	s.SetGauge("myGauge", math.NaN())
+	s.SetGauge("myGauge", math.Inf(1))
+
+	if s.Gauges["myGauge"] != 2 {
+		t.Errorf("Gauge expected to ignore NaN, got: %f", s.Gauges["myGauge"])
+	}
+
+	if result := s.Dump(); result == "{}" {
+		t.Errorf("Dump failed after a NaN gauge")
+	}
+}
+
 func TestRollingMetricNewValue(t *testing.T) {
 	// Test setting a single value for a rolling metric.
 	//
@@ -143,3 +262,105 @@ func TestMultipleRollingMetrics(t *testing.T) {
 		t.Errorf("Metric increment failed")
 	}
 }
+
+func TestRollingPercentiles(t *testing.T) {
+	// Test percentiles are reported for rolling metrics, and can be
+	// configured.
+	metricName := "myRollingMetric"
+
+	var s State
+	s.Info("test", 4)
+	s.SetPercentiles(50, 99.9)
+
+	for i := 1; i <= 4; i++ {
+		s.UpdateRollingMetric(metricName, float64(i))
+	}
+	result := s.Dump()
+
+	searchFor := "\"p50\": 2"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Rolling percentile p50 missing")
+	}
+
+	searchFor = "\"p99.9\": 4"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Rolling percentile p99.9 missing")
+	}
+}
+
+func TestRollingPercentilesDisabled(t *testing.T) {
+	// Test calling SetPercentiles with no values turns percentiles off.
+	//
+	var s State
+	s.Info("test", 4)
+	s.SetPercentiles()
+
+	s.UpdateRollingMetric("myRollingMetric", 1)
+	result := s.Dump()
+
+	searchFor := "\"RollingPercentiles\": null"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Rolling percentiles reported when disabled")
+	}
+}
+
+func TestRollingPercentilesTurnedOff(t *testing.T) {
+	// Test turning percentiles off drops values already reported.
+	//
+	var s State
+	s.Info("test", 4)
+
+	s.UpdateRollingMetric("myRollingMetric", 1)
+	s.Dump()
+	s.SetPercentiles()
+	result := s.Dump()
+
+	searchFor := "\"RollingPercentiles\": null"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Rolling percentiles still reported after turning off")
+	}
+}
+
+func TestEWMAMetrics(t *testing.T) {
+	// Test the EWMA is seeded with the first value and then smoothed by
+	// the configured alpha.
+	metricName := "myRollingMetric"
+
+	var s State
+	s.Info("test", 10)
+	s.SetEWMAAlpha(0.5)
+
+	s.UpdateRollingMetric(metricName, 4.0)
+	if s.EWMAMetrics[metricName] != 4.0 {
+		t.Errorf("EWMA expected to be seeded with 4, got: %f", s.EWMAMetrics[metricName])
+	}
+
+	s.UpdateRollingMetric(metricName, 2.0)
+	result := s.Dump()
+
+	searchFor := "\"" + metricName + "\": 3"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("EWMA expected 3 after second value, got: %f", s.EWMAMetrics[metricName])
+	}
+}
+
+func TestUpdateRollingMetricIgnoresNaN(t *testing.T) {
+	// Test a NaN or infinite data point is not recorded, so Dump keeps
+	// working afterwards.
+	metricName := "myRollingMetric"
+
+	var s State
+	s.Info("test", 3)
+
+	s.UpdateRollingMetric(metricName, math.NaN())
+	s.UpdateRollingMetric(metricName, math.Inf(-1))
+	s.UpdateRollingMetric(metricName, 2.0)
+
+	if s.EWMAMetrics[metricName] != 2.0 {
+		t.Errorf("EWMA expected to ignore NaN, got: %f", s.EWMAMetrics[metricName])
+	}
+
+	if result := s.Dump(); result == "{}" {
+		t.Errorf("Dump failed after a NaN data point")
+	}
+}
@@ -0,0 +1,86 @@
+package health
+
+import (
+	"bytes"
+	"net"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// identityData is the data made available to identity templates.
+type identityData struct {
+	Env      map[string]string
+	Hostname string
+	PodIP    string
+}
+
+// SetConfigTemplate renders an identity template and uses the result as
+// the identity string for this metrics instance. The template can refer
+// to environment variables, the hostname, and the pod IP, for example:
+//
+//	s.SetConfigTemplate("{{.Env.APP_NAME}}-{{.Hostname}}-{{.PodIP}}")
+//
+// PodIP is taken from the POD_IP environment variable (set via the k8s
+// downward API), falling back to the first non-loopback address.
+func (s *State) SetConfigTemplate(identityTemplate string) error {
+
+	t, err := template.New("identity").Option("missingkey=zero").Parse(identityTemplate)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, newIdentityData()); err != nil {
+		return err
+	}
+
+	identity := strings.TrimSpace(buf.String())
+	if len(identity) == 0 {
+		return nil // keep whatever identity we already have
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.Identity = identity
+	mu.Unlock() // end CRITICAL SECTION
+
+	return nil
+}
+
+func newIdentityData() identityData {
+
+	var d identityData
+
+	d.Env = make(map[string]string)
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			d.Env[parts[0]] = parts[1]
+		}
+	}
+
+	d.Hostname, _ = os.Hostname()
+
+	d.PodIP = os.Getenv("POD_IP")
+	if len(d.PodIP) == 0 {
+		d.PodIP = firstNonLoopbackIP()
+	}
+
+	return d
+}
+
+func firstNonLoopbackIP() string {
+
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return ""
+	}
+
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+			return ipNet.IP.String()
+		}
+	}
+	return ""
+}
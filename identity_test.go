@@ -0,0 +1,43 @@
+package health
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSetConfigTemplate(t *testing.T) {
+	// Test the identity template is rendered with env and pod IP values.
+	//
+	os.Setenv("APP_NAME", "billing")
+	os.Setenv("POD_IP", "10.1.2.3")
+	defer os.Unsetenv("APP_NAME")
+	defer os.Unsetenv("POD_IP")
+
+	var s State
+	s.Info("test", 10)
+
+	err := s.SetConfigTemplate("{{.Env.APP_NAME}}-{{.PodIP}}")
+	if err != nil {
+		t.Fatalf("SetConfigTemplate returned error: %s", err)
+	}
+
+	if s.Identity != "billing-10.1.2.3" {
+		t.Errorf("SetConfigTemplate failed to set Identity, got: %s", s.Identity)
+	}
+}
+
+func TestSetConfigTemplateBadTemplate(t *testing.T) {
+	// Test a template that does not parse returns an error and leaves
+	// the identity unchanged.
+	var s State
+	s.Info("test", 10)
+
+	err := s.SetConfigTemplate("{{.Env.APP_NAME")
+	if err == nil {
+		t.Errorf("SetConfigTemplate should fail on a bad template")
+	}
+
+	if s.Identity != "test" {
+		t.Errorf("SetConfigTemplate changed Identity on error, got: %s", s.Identity)
+	}
+}
@@ -0,0 +1,41 @@
+package health
+
+// Names of the package's own metrics, shown under HealthInternal in Dump().
+const (
+	internalDroppedMetrics     = "dropped_metrics"
+	internalCounterWraps       = "counter_wraps"
+	internalAlertsFired        = "alerts_fired"
+	internalAlertQueueDepth    = "alert_queue_depth"
+	internalAlertWebhookErrors = "alert_webhook_errors"
+	internalPushErrors         = "push_errors"
+	internalPushDurationMs     = "push_duration_ms"
+	internalPushLastSuccess    = "push_last_success"
+)
+
+// incrInternal adds n to one of the package's own metrics. Must be called
+// from within the critical section.
+func (s *State) incrInternal(name string, n float64) {
+
+	if s.HealthInternal == nil {
+		s.HealthInternal = make(map[string]float64)
+	}
+	s.HealthInternal[name] += n
+}
+
+// setInternal sets one of the package's own metrics. Must be called from
+// within the critical section.
+func (s *State) setInternal(name string, value float64) {
+
+	if s.HealthInternal == nil {
+		s.HealthInternal = make(map[string]float64)
+	}
+	s.HealthInternal[name] = value
+}
+
+// recordInternal is incrInternal for callers outside the critical section.
+func (s *State) recordInternal(name string, n float64) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.incrInternal(name, n)
+	mu.Unlock() // end CRITICAL SECTION
+}
@@ -0,0 +1,30 @@
+package health
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHealthInternalInDump(t *testing.T) {
+	// Test the package's own metrics show in Dump, separate from the
+	// application's metrics.
+	var s State
+	s.Info("test", 1)
+	s.SetMaxMetrics(1)
+	s.AddAlertRule(AlertRule{Name: "slow", Metric: "latency", Op: ">", Threshold: 500})
+
+	s.IncrMetric("requests")
+	s.IncrMetric("user-1")
+	s.UpdateRollingMetric("latency", 600)
+	result := s.Dump()
+
+	for _, searchFor := range []string{
+		"\"HealthInternal\": {",
+		"\"dropped_metrics\": 1",
+		"\"alerts_fired\": 1",
+	} {
+		if strings.Index(result, searchFor) < 0 {
+			t.Errorf("Dump missing %s", searchFor)
+		}
+	}
+}
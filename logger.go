@@ -0,0 +1,40 @@
+package health
+
+import "log"
+
+// Logger is used for the package's internal warnings and errors. A
+// *log.Logger satisfies Logger, as does the *log.Logger returned by
+// slog.NewLogLogger for services using structured logging.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// SetLogger routes this State's warnings and errors to logger, instead of
+// the standard logger.
+func (s *State) SetLogger(logger Logger) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.logger = logger
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// logf writes to the State's logger, or the standard logger if none is
+// set. Must be called outside the critical section, so a Logger can record
+// metrics on the same State.
+func (s *State) logf(format string, v ...interface{}) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	logger := s.logger
+	mu.Unlock() // end CRITICAL SECTION
+
+	logf(logger, format, v...)
+}
+
+func logf(logger Logger, format string, v ...interface{}) {
+
+	if logger == nil {
+		log.Printf(format, v...)
+		return
+	}
+	logger.Printf(format, v...)
+}
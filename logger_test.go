@@ -0,0 +1,106 @@
+package health
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetLogger(t *testing.T) {
+	// Test internal warnings go to the configured logger.
+	//
+	var buf bytes.Buffer
+
+	var s State
+	s.Info("test", 10)
+	s.SetLogger(log.New(&buf, "", 0))
+
+	s.IncrMetric("myMetric")
+	s.Metrics["myMetric"] = maxMetricValue
+	s.IncrMetric("myMetric")
+
+	if strings.Index(buf.String(), "myMetric wrapped around") < 0 {
+		t.Errorf("Warning not sent to configured logger, got: %s", buf.String())
+	}
+}
+
+func TestDumpDoesNotExitOnError(t *testing.T) {
+	// Test a Dump that can't be marshalled logs the error and returns an
+	// empty object, rather than exiting the process.
+	var buf bytes.Buffer
+
+	var s State
+	s.Info("test", 1)
+	s.SetLogger(log.New(&buf, "", 0))
+
+	// bypass SetGauge, which rejects values JSON can't encode
+	s.SetGauge("myGauge", 1)
+	s.Gauges["myGauge"] = math.Inf(1)
+
+	if result := s.Dump(); result != "{}" {
+		t.Errorf("Dump expected {} on marshalling error, got: %s", result)
+	}
+
+	if strings.Index(buf.String(), "JSON Marshalling failed") < 0 {
+		t.Errorf("Marshalling error not logged")
+	}
+}
+
+// metricLogger counts log lines as a metric on the State it logs for.
+type metricLogger struct{ s *State }
+
+func (l metricLogger) Printf(format string, v ...interface{}) {
+	l.s.IncrMetric("log_lines")
+}
+
+func TestLoggerCanRecordMetrics(t *testing.T) {
+	// Test a Logger that records a metric on the same State doesn't
+	// deadlock, as the logger is called outside the lock.
+	var s State
+	s.Info("test", 10)
+	s.SetLogger(metricLogger{&s})
+
+	done := make(chan struct{})
+	go func() {
+		s.IncrMetric("myMetric")
+		s.Metrics["myMetric"] = maxMetricValue
+		s.IncrMetric("myMetric")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Logger recording a metric deadlocked")
+	}
+
+	if s.Metrics["log_lines"] != 1 {
+		t.Errorf("Logger expected to record 1 log line, got: %d", s.Metrics["log_lines"])
+	}
+}
+
+func TestSetLoggerWhileDumping(t *testing.T) {
+	// Test the logger can be replaced while Dump logs, without a data
+	// race (run with -race).
+	var s State
+	s.Info("test", 1)
+	s.SetGauge("myGauge", 1)
+	s.Gauges["myGauge"] = math.Inf(1)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			s.Dump()
+		}
+		close(done)
+	}()
+
+	for i := 0; i < 10; i++ {
+		s.SetLogger(log.New(ioutil.Discard, "", 0))
+	}
+	<-done
+}
@@ -0,0 +1,41 @@
+package health
+
+import "net/http"
+
+// defaultMaintenanceCode is the StatusHandler response code while in
+// maintenance, unless SetMaintenanceStatusCode is called.
+const defaultMaintenanceCode = http.StatusServiceUnavailable
+
+// SetMaintenance marks this instance as in maintenance for reason, which
+// is shown in Dump() and the status endpoint. Alert rules are not
+// evaluated during maintenance, so planned work doesn't page anyone. An
+// empty reason ends maintenance.
+func (s *State) SetMaintenance(reason string) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	previous := s.Maintenance
+	s.Maintenance = reason
+	mu.Unlock() // end CRITICAL SECTION
+
+	switch {
+	case len(previous) == 0 && len(reason) > 0:
+		s.publish(EventInfo{Event: EventMaintenanceStarted, Detail: reason})
+	case len(previous) > 0 && len(reason) == 0:
+		s.publish(EventInfo{Event: EventMaintenanceEnded, Detail: previous})
+	}
+}
+
+// SetMaintenanceStatusCode sets the code StatusHandler responds with while
+// in maintenance, for load balancers that should keep (200) or drain (503)
+// the instance.
+func (s *State) SetMaintenanceStatusCode(code int) {
+
+	if code < 100 || code > 599 {
+		s.strictf("health: SetMaintenanceStatusCode called with %d", code)
+		return
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.maintenanceCode = code
+	mu.Unlock() // end CRITICAL SECTION
+}
@@ -0,0 +1,57 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetMaintenance(t *testing.T) {
+	// Test the maintenance reason shows in Dump and the status endpoint,
+	// with the configured status code.
+	var s State
+	s.Info("test", 10)
+	s.SetMaintenance("database migration")
+
+	result := s.Dump()
+	searchFor := "\"Maintenance\": \"database migration\""
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Dump missing maintenance reason")
+	}
+
+	rec := httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Maintenance expected default 503, got: %d", rec.Code)
+	}
+
+	s.SetMaintenanceStatusCode(http.StatusOK)
+	rec = httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Maintenance expected configured 200, got: %d", rec.Code)
+	}
+
+	s.SetMaintenance("")
+	s.SetMaintenanceStatusCode(http.StatusTeapot)
+	rec = httptest.NewRecorder()
+	s.StatusHandler(rec, httptest.NewRequest("GET", "/health/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Ended maintenance expected 200, got: %d", rec.Code)
+	}
+}
+
+func TestMaintenanceSuppressesAlerts(t *testing.T) {
+	// Test alert rules are not evaluated during maintenance.
+	//
+	var s State
+	s.Info("test", 1)
+	s.AddAlertRule(AlertRule{Name: "slow", Metric: "latency", Op: ">", Threshold: 500})
+	s.SetMaintenance("load test")
+
+	s.UpdateRollingMetric("latency", 600)
+	if s.HealthInternal[internalAlertsFired] != 0 {
+		t.Errorf("Alert fired during maintenance")
+	}
+}
@@ -0,0 +1,209 @@
+package health
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unmatchedRoute is the route name used for requests no pattern matches,
+// so raw paths never become metric names.
+const unmatchedRoute = "unmatched"
+
+// defaultApdexThreshold is the Apdex T used unless SetApdexThreshold is
+// called.
+const defaultApdexThreshold = 500 * time.Millisecond
+
+// RouteNamer returns the route name to record a request under. It should
+// return a name derived from the route pattern, not the raw path, to keep
+// the number of metric names bounded.
+type RouteNamer func(r *http.Request) string
+
+// Middleware records a request count (http_requests, tagged by route and
+// status), response time in milliseconds (http_response_ms, tagged by
+// route) and an Apdex score (http_apdex, tagged by route) for every request
+// passed to next. The Apdex score averages only the requests seen so far,
+// so a new route is not scored down by empty slots. With a nil namer, every request is recorded under the
+// unmatched route.
+//
+// Pass the same patterns the mux is configured with to PatternNamer, e.g.
+//
+//	s.Middleware(mux, health.PatternNamer("/api/users/{id}", "/static/{path...}"))
+//
+// A router that exposes the matched pattern, such as chi or gorilla/mux,
+// can instead pass a namer that converts that pattern with RouteName.
+func (s *State) Middleware(next http.Handler, namer RouteNamer) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		duration := time.Since(start)
+		elapsed := float64(duration) / float64(time.Millisecond)
+
+		route := unmatchedRoute
+		if namer != nil {
+			if name := namer(r); len(name) > 0 {
+				route = name
+			}
+		}
+
+		s.IncrMetricWithTags("http_requests", map[string]string{
+			"route":  route,
+			"status": strconv.Itoa(rec.status),
+		})
+		s.UpdateRollingMetricWithTags("http_response_ms", map[string]string{"route": route}, elapsed)
+		s.updateRollingMetric(taggedName("http_apdex", map[string]string{"route": route}), s.apdexScore(duration, rec.status), true)
+	})
+}
+
+// SetApdexThreshold sets the Apdex T for the middleware. Requests faster
+// than T are satisfied, those faster than 4T are tolerating, and slower
+// requests or 5xx responses are frustrated. The default T is 500ms.
+func (s *State) SetApdexThreshold(t time.Duration) {
+
+	if t <= 0 {
+		s.strictf("health: SetApdexThreshold called with %s", t)
+		return
+	}
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.apdexThreshold = t
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// apdexScore scores one request as satisfied (1), tolerating (0.5) or
+// frustrated (0). The rolling average of the scores is the Apdex score.
+func (s *State) apdexScore(duration time.Duration, status int) float64 {
+
+	mu.Lock() // enter CRITICAL SECTION
+	t := s.apdexThreshold
+	mu.Unlock() // end CRITICAL SECTION
+
+	if t == 0 {
+		t = defaultApdexThreshold
+	}
+
+	switch {
+	case status >= 500:
+		return 0
+	case duration <= t:
+		return 1
+	case duration <= 4*t:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// RouteName converts a route pattern into a metric-friendly name, for
+// example "/api/users/{id}" becomes "api_users_id". A leading method, as in
+// Go 1.22 patterns ("GET /api/users/{id}"), is dropped.
+func RouteName(pattern string) string {
+
+	if i := strings.IndexByte(pattern, ' '); i >= 0 {
+		pattern = pattern[i+1:]
+	}
+
+	var b strings.Builder
+	underscore := false
+	for _, c := range strings.ToLower(pattern) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			if underscore && b.Len() > 0 {
+				b.WriteByte('_')
+			}
+			b.WriteRune(c)
+			underscore = false
+		} else {
+			underscore = true
+		}
+	}
+
+	if b.Len() == 0 {
+		return "root"
+	}
+	return b.String()
+}
+
+// PatternNamer returns a RouteNamer that matches the request path against
+// patterns such as "/api/users/{id}" or "/static/{path...}", and names the
+// request after the first pattern that matches. Go 1.22 ServeMux patterns
+// can be passed as they are: a leading method is ignored, and {$} anchors
+// the end of the path. Requests that match no
+// pattern are named "unmatched", guarding against high-cardinality paths.
+func PatternNamer(patterns ...string) RouteNamer {
+
+	type route struct {
+		segments []string
+		name     string
+	}
+
+	routes := make([]route, 0, len(patterns))
+	for _, p := range patterns {
+		path := p
+		if i := strings.IndexByte(path, ' '); i >= 0 { // drop a leading method
+			path = path[i+1:]
+		}
+		segments := splitPath(path)
+		if n := len(segments); n > 0 && segments[n-1] == "{$}" { // end anchor
+			segments = segments[:n-1]
+		}
+		routes = append(routes, route{segments: segments, name: RouteName(p)})
+	}
+
+	return func(r *http.Request) string {
+		path := splitPath(r.URL.Path)
+		for _, rt := range routes {
+			if matchSegments(rt.segments, path) {
+				return rt.name
+			}
+		}
+		return unmatchedRoute
+	}
+}
+
+func splitPath(path string) []string {
+
+	path = strings.Trim(path, "/")
+	if len(path) == 0 {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
+
+// matchSegments matches a path against a pattern, where {name} matches one
+// segment and {name...} matches the rest of the path.
+func matchSegments(pattern, path []string) bool {
+
+	for i, p := range pattern {
+		wildcard := strings.HasPrefix(p, "{") && strings.HasSuffix(p, "}")
+		if wildcard && strings.HasSuffix(p, "...}") {
+			return true
+		}
+		if i >= len(path) || (!wildcard && p != path[i]) {
+			return false
+		}
+	}
+	return len(pattern) == len(path)
+}
+
+// statusRecorder captures the status code written by a handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush passes through to the underlying ResponseWriter, so streaming
+// handlers keep working behind the middleware.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
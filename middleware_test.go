@@ -0,0 +1,168 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRouteName(t *testing.T) {
+	// Test route patterns become metric-friendly names.
+	//
+	tests := map[string]string{
+		"/api/users/{id}":            "api_users_id",
+		"GET /api/users/{id}":        "api_users_id",
+		"/api/users/{id:[0-9]+}/":    "api_users_id_0_9",
+		"/static/{path...}":          "static_path",
+		"/":                          "root",
+		"/Orders/{orderID}/Invoices": "orders_orderid_invoices",
+	}
+
+	for pattern, want := range tests {
+		if got := RouteName(pattern); got != want {
+			t.Errorf("RouteName(%q) expected %s, got: %s", pattern, want, got)
+		}
+	}
+}
+
+func TestPatternNamer(t *testing.T) {
+	// Test paths are named after the matching pattern, and unknown paths
+	// don't become metric names.
+	namer := PatternNamer("/api/users/{id}", "/static/{path...}", "GET /orders/{id}", "/api/{$}", "/")
+
+	tests := map[string]string{
+		"/api/users/123":      "api_users_id",
+		"/api/users/123/":     "api_users_id",
+		"/static/css/app.css": "static_path",
+		"/orders/7":           "orders_id",
+		"/api/":               "api",
+		"/api/other":          "unmatched",
+		"/":                   "root",
+		"/api/users/123/edit": "unmatched",
+		"/wp-login.php":       "unmatched",
+	}
+
+	for path, want := range tests {
+		if got := namer(httptest.NewRequest("GET", path, nil)); got != want {
+			t.Errorf("PatternNamer(%q) expected %s, got: %s", path, want, got)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	// Test requests are counted per route and status.
+	//
+	var s State
+	s.Info("test", 10)
+
+	handler := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/users/0" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}), PatternNamer("/api/users/{id}"))
+
+	for _, path := range []string{"/api/users/1", "/api/users/2", "/api/users/0", "/other"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	if s.Metrics[`http_requests{route="api_users_id",status="200"}`] != 2 {
+		t.Errorf("Middleware failed to count 200s, got: %v", s.Metrics)
+	}
+
+	if s.Metrics[`http_requests{route="api_users_id",status="404"}`] != 1 {
+		t.Errorf("Middleware failed to count 404s, got: %v", s.Metrics)
+	}
+
+	if s.Metrics[`http_requests{route="unmatched",status="200"}`] != 1 {
+		t.Errorf("Middleware failed to count unmatched route, got: %v", s.Metrics)
+	}
+
+	if _, ok := s.RollingMetrics[`http_response_ms{route="api_users_id"}`]; !ok {
+		t.Errorf("Middleware failed to record response time")
+	}
+}
+
+func TestApdexScore(t *testing.T) {
+	// Test requests are scored against the configured Apdex T.
+	//
+	var s State
+	s.Info("test", 10)
+	s.SetApdexThreshold(100 * time.Millisecond)
+
+	tests := []struct {
+		duration time.Duration
+		status   int
+		want     float64
+	}{
+		{50 * time.Millisecond, http.StatusOK, 1},
+		{300 * time.Millisecond, http.StatusOK, 0.5},
+		{time.Second, http.StatusOK, 0},
+		{50 * time.Millisecond, http.StatusInternalServerError, 0},
+	}
+
+	for _, test := range tests {
+		if got := s.apdexScore(test.duration, test.status); got != test.want {
+			t.Errorf("Apdex score for %s/%d expected %f, got: %f", test.duration, test.status, test.want, got)
+		}
+	}
+}
+
+func TestMiddlewareApdex(t *testing.T) {
+	// Test the rolling Apdex per route reflects satisfied and frustrated
+	// requests.
+	var s State
+	s.Info("test", 2)
+
+	handler := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}), PatternNamer("/ok", "/fail"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/fail", nil))
+
+	if s.RollingMetrics[`http_apdex{route="ok"}`] != 1 {
+		t.Errorf("Apdex for fast route expected 1, got: %f", s.RollingMetrics[`http_apdex{route="ok"}`])
+	}
+
+	if s.RollingMetrics[`http_apdex{route="fail"}`] != 0 {
+		t.Errorf("Apdex for failing route expected 0, got: %f", s.RollingMetrics[`http_apdex{route="fail"}`])
+	}
+}
+
+func TestMiddlewareWithoutInfo(t *testing.T) {
+	// Test the middleware records on a State that Info was never called
+	// on, rather than panicking while holding the lock.
+	var s State
+
+	handler := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), nil)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if s.RollingDataSize != defaultRollingDataSize {
+		t.Errorf("RollingDataSize expected default %d, got: %d", defaultRollingDataSize, s.RollingDataSize)
+	}
+
+	if _, ok := s.RollingMetrics[`http_response_ms{route="unmatched"}`]; !ok {
+		t.Errorf("Middleware failed to record response time without Info")
+	}
+}
+
+func TestMiddlewareApdexPartialWindow(t *testing.T) {
+	// Test the Apdex of a route with fewer requests than the rolling data
+	// size is not pulled down by the empty slots.
+	var s State
+	s.Info("test", 10)
+
+	handler := s.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), PatternNamer("/ok"))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/ok", nil))
+
+	if s.RollingMetrics[`http_apdex{route="ok"}`] != 1 {
+		t.Errorf("Apdex for two satisfied requests expected 1, got: %f", s.RollingMetrics[`http_apdex{route="ok"}`])
+	}
+}
@@ -0,0 +1,217 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pusher defaults, used when the matching field is left at zero.
+const (
+	defaultPushInterval = time.Minute
+	defaultPushBackoff  = time.Second
+	defaultPushTimeout  = 10 * time.Second
+)
+
+// Pusher periodically POSTs a State's Dump() to a collector, giving a
+// "many agents, one collector" topology where only the collector needs to
+// be scraped. Set the exported fields before calling Start.
+type Pusher struct {
+	URL      string
+	Token    string        // sent as a bearer token, optional
+	Interval time.Duration // time between pushes, default 1m
+	Retries  int           // retries after a failed push, default 0
+	Backoff  time.Duration // wait before the first retry, doubling each time, default 1s
+	Jitter   time.Duration // random extra wait added to each interval, default 0
+
+	state  *State
+	client http.Client
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPusher returns a Pusher that sends s to url.
+func NewPusher(s *State, url string) *Pusher {
+	return &Pusher{
+		URL:    url,
+		state:  s,
+		client: http.Client{Timeout: defaultPushTimeout},
+	}
+}
+
+// Start begins pushing in the background, once every Interval plus up to
+// Jitter. The first push comes after a random part of Interval, so a fleet
+// started together does not push in lockstep. Calling Start on a running
+// Pusher does nothing.
+func (p *Pusher) Start() {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.cancel != nil {
+		return
+	}
+
+	interval := p.Interval
+	if interval <= 0 {
+		interval = defaultPushInterval
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.cancel = cancel
+	p.done = make(chan struct{})
+	go p.run(ctx, interval, p.Jitter, p.done)
+}
+
+// Stop ends background pushing, abandoning any push in progress, and
+// waits for the background goroutine to exit.
+func (p *Pusher) Stop() {
+
+	p.mu.Lock()
+	cancel, done := p.cancel, p.done
+	p.cancel, p.done = nil, nil
+	p.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Shutdown stops background pushing and makes a final push, so the
+// collector has the latest metrics, giving up when ctx is done.
+func (p *Pusher) Shutdown(ctx context.Context) error {
+
+	p.Stop()
+
+	if err := p.pushContext(ctx); err != nil {
+		return fmt.Errorf("health: final push to %s failed: %s", p.URL, err)
+	}
+	return nil
+}
+
+func (p *Pusher) run(ctx context.Context, interval, jitter time.Duration, done chan<- struct{}) {
+
+	defer close(done)
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	wait := time.Duration(rng.Int63n(int64(interval))) + 1 // stagger the first push
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			err := p.pushContext(ctx)
+			if err != nil && ctx.Err() == nil {
+				p.state.logf("health: push to %s failed: %s", p.URL, err)
+			}
+
+			wait = interval
+			if jitter > 0 {
+				wait += time.Duration(rng.Int63n(int64(jitter)))
+			}
+			timer.Reset(wait)
+		}
+	}
+}
+
+// Push sends the current Dump() once, retrying with backoff on network
+// errors, 429 and 5xx responses. The outcome is recorded in the State's
+// HealthInternal metrics.
+func (p *Pusher) Push() error {
+	return p.pushContext(context.Background())
+}
+
+func (p *Pusher) pushContext(ctx context.Context) error {
+
+	start := time.Now()
+	err := p.push(ctx)
+
+	mu.Lock() // enter CRITICAL SECTION
+	if err != nil {
+		p.state.incrInternal(internalPushErrors, 1)
+	} else {
+		p.state.setInternal(internalPushLastSuccess, float64(start.Unix()))
+	}
+	p.state.setInternal(internalPushDurationMs, float64(time.Since(start))/float64(time.Millisecond))
+	mu.Unlock() // end CRITICAL SECTION
+
+	info := EventInfo{Event: EventPushCompleted, Name: p.URL, Time: start.Unix()}
+	if err != nil {
+		info.Event = EventPushFailed
+		info.Detail = err.Error()
+	}
+	p.state.publish(info)
+
+	return err
+}
+
+func (p *Pusher) push(ctx context.Context) error {
+
+	body := p.state.Dump()
+
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = defaultPushBackoff
+	}
+
+	var err error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		var retry bool
+		retry, err = p.post(ctx, body)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post makes one attempt, returning whether a failure is worth retrying.
+func (p *Pusher) post(ctx context.Context, body string) (bool, error) {
+
+	req, err := http.NewRequest("POST", p.URL, bytes.NewBufferString(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if len(p.Token) > 0 {
+		req.Header.Set("Authorization", "Bearer "+p.Token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, errors.New(strings.ToLower(resp.Status))
+	default:
+		return false, errors.New(strings.ToLower(resp.Status))
+	}
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPush(t *testing.T) {
+	// Test a push sends the Dump() with the bearer token.
+	//
+	var body, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := ioutil.ReadAll(r.Body)
+		body = string(data)
+		auth = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	var s State
+	s.Info("worker-1", 10)
+	s.IncrMetric("myMetric")
+
+	p := NewPusher(&s, server.URL)
+	p.Token = "secret"
+
+	if err := p.Push(); err != nil {
+		t.Fatalf("Push returned error: %s", err)
+	}
+
+	if strings.Index(body, "\"myMetric\": 1") < 0 {
+		t.Errorf("Push body missing metric, got: %s", body)
+	}
+
+	if auth != "Bearer secret" {
+		t.Errorf("Push sent wrong Authorization header: %s", auth)
+	}
+
+	if s.HealthInternal[internalPushLastSuccess] == 0 {
+		t.Errorf("Push failed to record last success")
+	}
+}
+
+func TestPushRetries(t *testing.T) {
+	// Test 5xx responses are retried, while 4xx responses are not.
+	//
+	var mu sync.Mutex
+	calls := 0
+	status := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+		if calls < 3 {
+			w.WriteHeader(status)
+		}
+	}))
+	defer server.Close()
+
+	var s State
+	s.Info("worker-1", 10)
+
+	p := NewPusher(&s, server.URL)
+	p.Retries = 2
+	p.Backoff = time.Millisecond
+
+	if err := p.Push(); err != nil || calls != 3 {
+		t.Errorf("Push expected success on third attempt, got: %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	status = http.StatusUnauthorized
+	if err := p.Push(); err == nil || calls != 1 {
+		t.Errorf("Push expected no retry on 401, got: %v after %d calls", err, calls)
+	}
+
+	if s.HealthInternal[internalPushErrors] != 1 {
+		t.Errorf("Push failed to record error, got: %f", s.HealthInternal[internalPushErrors])
+	}
+}
+
+func TestPusherStartStop(t *testing.T) {
+	// Test background pushing runs every interval, with jitter, until
+	// stopped.
+	//
+	pushes := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes <- struct{}{}
+	}))
+	defer server.Close()
+
+	var s State
+	s.Info("worker-1", 10)
+
+	p := NewPusher(&s, server.URL)
+	p.Interval = 5 * time.Millisecond
+	p.Jitter = 5 * time.Millisecond
+	p.Start()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-pushes:
+		case <-time.After(time.Second):
+			t.Errorf("Pusher did not push after Start")
+		}
+	}
+
+	p.Stop()
+	p.Stop() // safe to call twice
+}
@@ -0,0 +1,91 @@
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// maxRawSamples caps how many raw samples are kept per metric, however
+// long the retention period.
+const maxRawSamples = 10000
+
+// Sample is a single raw data point given to UpdateRollingMetric.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// SetRawSampleRetention turns on keeping the raw data points of rolling
+// metrics for the last retention period, so individual values can be seen
+// while debugging. A retention of zero turns it off and drops any samples
+// already kept.
+func (s *State) SetRawSampleRetention(retention time.Duration) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.rawRetention = retention
+	if retention <= 0 {
+		s.rawSamples = nil
+	}
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// RawSamples returns the raw data points kept for a rolling metric, oldest
+// first.
+func (s *State) RawSamples(name string) []Sample {
+
+	mu.Lock() // enter CRITICAL SECTION
+	defer mu.Unlock()
+
+	samples := pruneSamples(s.rawSamples[name], time.Now().Add(-s.rawRetention))
+	result := make([]Sample, len(samples))
+	copy(result, samples)
+
+	return result
+}
+
+// RawSamplesHandler writes the raw samples for the metric named in the
+// metric query parameter as JSON.
+func (s *State) RawSamplesHandler(w http.ResponseWriter, r *http.Request) {
+
+	name := r.URL.Query().Get("metric")
+	if len(name) < 1 {
+		http.Error(w, "metric parameter required", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.RawSamples(name))
+}
+
+// addRawSample keeps value if raw sample retention is on. Must be called
+// from within the critical section.
+func (s *State) addRawSample(name string, value float64) {
+
+	if s.rawRetention <= 0 {
+		return
+	}
+
+	if s.rawSamples == nil {
+		s.rawSamples = make(map[string][]Sample)
+	}
+
+	now := time.Now()
+	samples := pruneSamples(s.rawSamples[name], now.Add(-s.rawRetention))
+	if len(samples) >= maxRawSamples {
+		samples = samples[len(samples)-maxRawSamples+1:]
+	}
+	s.rawSamples[name] = append(samples, Sample{Time: now, Value: value})
+}
+
+// pruneSamples drops samples from before cutoff. Samples are in time order,
+// so everything up to the first sample at or after cutoff goes.
+func pruneSamples(samples []Sample, cutoff time.Time) []Sample {
+
+	for i, sample := range samples {
+		if !sample.Time.Before(cutoff) {
+			return samples[i:]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRawSamplesOptIn(t *testing.T) {
+	// Test raw samples are only kept once retention is turned on.
+	//
+	metricName := "responseTime"
+
+	var s State
+	s.Info("test", 10)
+
+	s.UpdateRollingMetric(metricName, 1.0)
+	if len(s.RawSamples(metricName)) != 0 {
+		t.Errorf("Raw samples kept without retention set")
+	}
+
+	s.SetRawSampleRetention(time.Minute)
+	s.UpdateRollingMetric(metricName, 2.0)
+	s.UpdateRollingMetric(metricName, 3.0)
+
+	samples := s.RawSamples(metricName)
+	if len(samples) != 2 || samples[0].Value != 2.0 || samples[1].Value != 3.0 {
+		t.Errorf("Raw samples expected [2 3], got: %+v", samples)
+	}
+}
+
+func TestRawSamplesExpire(t *testing.T) {
+	// Test samples older than the retention period are dropped.
+	//
+	metricName := "responseTime"
+
+	var s State
+	s.Info("test", 10)
+	s.SetRawSampleRetention(time.Minute)
+
+	s.UpdateRollingMetric(metricName, 1.0)
+	s.rawSamples[metricName][0].Time = time.Now().Add(-time.Hour)
+	s.UpdateRollingMetric(metricName, 2.0)
+
+	samples := s.RawSamples(metricName)
+	if len(samples) != 1 || samples[0].Value != 2.0 {
+		t.Errorf("Raw samples expected [2], got: %+v", samples)
+	}
+}
+
+func TestRawSamplesHandler(t *testing.T) {
+	// Test the handler returns samples for the named metric, and needs
+	// a metric name.
+	var s State
+	s.Info("test", 10)
+	s.SetRawSampleRetention(time.Minute)
+	s.UpdateRollingMetric("responseTime", 42.0)
+
+	rec := httptest.NewRecorder()
+	s.RawSamplesHandler(rec, httptest.NewRequest("GET", "/health/raw?metric=responseTime", nil))
+	if strings.Index(rec.Body.String(), "\"Value\":42") < 0 {
+		t.Errorf("RawSamplesHandler missing sample, got: %s", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.RawSamplesHandler(rec, httptest.NewRequest("GET", "/health/raw", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("RawSamplesHandler expected 400 without metric, got: %d", rec.Code)
+	}
+}
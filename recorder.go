@@ -0,0 +1,72 @@
+package health
+
+// Recorder is the set of methods used to record metrics. State satisfies
+// Recorder, so third-party libraries can instrument against the interface
+// and be handed either a State or a prefixed recorder.
+type Recorder interface {
+	IncrMetric(name string)
+	IncrMetricBy(name string, n int)
+	DecrMetric(name string)
+	IncrMetricWithTags(name string, tags map[string]string)
+	SetGauge(name string, value float64)
+	UpdateRollingMetric(name string, value float64)
+	UpdateRollingMetricWithTags(name string, tags map[string]string, value float64)
+}
+
+// prefixedRecorder prefixes every metric name before passing it on.
+type prefixedRecorder struct {
+	prefix string
+	state  *State
+}
+
+// WithPrefix returns a Recorder that prefixes all metric names with
+// prefix, so an embedded library can't collide with application metric
+// names. An empty name is still ignored, rather than recording the bare
+// prefix.
+func (s *State) WithPrefix(prefix string) Recorder {
+	return &prefixedRecorder{prefix: prefix, state: s}
+}
+
+func (p *prefixedRecorder) name(name string) string {
+
+	if len(name) < 1 { // no name, no entry
+		return ""
+	}
+	return p.prefix + name
+}
+
+// IncrMetric increments the prefixed counter metric by one.
+func (p *prefixedRecorder) IncrMetric(name string) {
+	p.state.IncrMetric(p.name(name))
+}
+
+// IncrMetricBy adds n to the prefixed counter metric.
+func (p *prefixedRecorder) IncrMetricBy(name string, n int) {
+	p.state.IncrMetricBy(p.name(name), n)
+}
+
+// DecrMetric decrements the prefixed counter metric by one.
+func (p *prefixedRecorder) DecrMetric(name string) {
+	p.state.DecrMetric(p.name(name))
+}
+
+// IncrMetricWithTags increments the prefixed counter metric for these tags.
+func (p *prefixedRecorder) IncrMetricWithTags(name string, tags map[string]string) {
+	p.state.IncrMetricWithTags(p.name(name), tags)
+}
+
+// SetGauge sets the prefixed gauge metric to value.
+func (p *prefixedRecorder) SetGauge(name string, value float64) {
+	p.state.SetGauge(p.name(name), value)
+}
+
+// UpdateRollingMetric adds a data point to the prefixed rolling metric.
+func (p *prefixedRecorder) UpdateRollingMetric(name string, value float64) {
+	p.state.UpdateRollingMetric(p.name(name), value)
+}
+
+// UpdateRollingMetricWithTags adds a data point to the prefixed rolling
+// metric for these tags.
+func (p *prefixedRecorder) UpdateRollingMetricWithTags(name string, tags map[string]string, value float64) {
+	p.state.UpdateRollingMetricWithTags(p.name(name), tags, value)
+}
@@ -0,0 +1,43 @@
+package health
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWithPrefix(t *testing.T) {
+	// Test metrics recorded through a prefixed recorder are namespaced.
+	//
+	var s State
+	s.Info("test", 10)
+
+	var r Recorder = s.WithPrefix("libfoo_")
+	r.IncrMetric("requests")
+	r.UpdateRollingMetric("latency", 1.0)
+	result := s.Dump()
+
+	searchFor := "\"libfoo_requests\": 1"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Prefixed IncrMetric failed")
+	}
+
+	searchFor = "\"libfoo_latency\": 0.1"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Prefixed UpdateRollingMetric failed")
+	}
+}
+
+func TestWithPrefixIgnoresEmptyName(t *testing.T) {
+	// Test an empty name is ignored rather than recorded as the prefix.
+	//
+	var s State
+	s.Info("test", 10)
+
+	s.WithPrefix("libfoo_").IncrMetric("")
+	result := s.Dump()
+
+	searchFor := "\"Metrics\": null"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Prefixed IncrMetric recorded an empty name")
+	}
+}
@@ -0,0 +1,94 @@
+package health
+
+import (
+	"encoding/json"
+	"sync"
+)
+
+// Registry holds multiple named State instances, so a modular monolith
+// can keep the metrics of each module isolated while still serving a
+// single health surface.
+type Registry struct {
+	mu     sync.Mutex
+	states map[string]*State
+	logger Logger
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{states: make(map[string]*State)}
+}
+
+// Register adds a State under name, replacing any existing State
+// registered with the same name.
+func (r *Registry) Register(name string, s *State) {
+
+	if len(name) < 1 || s == nil { // no name, no entry
+		return
+	}
+
+	r.mu.Lock()
+	r.states[name] = s
+	r.mu.Unlock()
+}
+
+// Unregister removes the State registered under name, if there is one.
+func (r *Registry) Unregister(name string) {
+
+	r.mu.Lock()
+	delete(r.states, name)
+	r.mu.Unlock()
+}
+
+// Get returns the State registered under name, or nil if there is none.
+func (r *Registry) Get(name string) *State {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.states[name]
+}
+
+// Names returns the names of all registered states.
+func (r *Registry) Names() []string {
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	names := make([]string, 0, len(r.states))
+	for name := range r.states {
+		names = append(names, name)
+	}
+	return names
+}
+
+// SetLogger routes the Registry's warnings and errors to logger, instead
+// of the standard logger.
+func (r *Registry) SetLogger(logger Logger) {
+
+	r.mu.Lock()
+	r.logger = logger
+	r.mu.Unlock()
+}
+
+// Dump returns a JSON byte-string of every registered State, keyed by
+// name, or an empty JSON object if marshalling fails.
+func (r *Registry) Dump() string {
+
+	r.mu.Lock()
+	mu.Lock() // enter CRITICAL SECTION
+	for _, s := range r.states {
+		s.updatePercentiles()
+	}
+	data, err := json.MarshalIndent(r.states, "", "    ")
+	mu.Unlock() // end CRITICAL SECTION
+	logger := r.logger
+	r.mu.Unlock()
+
+	if err != nil {
+		logf(logger, "health: JSON Marshalling failed: %s", err)
+		return "{}"
+	}
+
+	return string(data)
+}
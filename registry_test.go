@@ -0,0 +1,58 @@
+package health
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryRegisterAndGet(t *testing.T) {
+	// Test registering a State and fetching it back by name.
+	//
+	var s State
+	s.Info("billing", 10)
+
+	r := NewRegistry()
+	r.Register("billing", &s)
+
+	if r.Get("billing") != &s {
+		t.Errorf("Registry failed to return registered State")
+	}
+
+	if r.Get("missing") != nil {
+		t.Errorf("Registry returned a State for an unknown name")
+	}
+
+	r.Unregister("billing")
+	if r.Get("billing") != nil {
+		t.Errorf("Registry returned a State after Unregister")
+	}
+}
+
+func TestRegistryDump(t *testing.T) {
+	// Test the combined Dump contains each module's metrics under its name.
+	//
+	var billing, search State
+	billing.Info("billing", 10)
+	search.Info("search", 10)
+
+	billing.IncrMetric("invoices")
+	search.IncrMetric("queries")
+	search.UpdateRollingMetric("latency", 1)
+
+	r := NewRegistry()
+	r.Register("billing", &billing)
+	r.Register("search", &search)
+	result := r.Dump()
+
+	for _, searchFor := range []string{
+		"\"billing\": {",
+		"\"search\": {",
+		"\"invoices\": 1",
+		"\"queries\": 1",
+		"\"p99\": 1",
+	} {
+		if strings.Index(result, searchFor) < 0 {
+			t.Errorf("Registry Dump missing %s", searchFor)
+		}
+	}
+}
@@ -1,7 +1,14 @@
 package health
 
+import (
+	"math"
+	"sort"
+	"time"
+)
+
 type rollingMetric struct {
 	data  []float64
+	times []time.Time // when each data point was added
 	index int
 }
 
@@ -16,6 +23,11 @@ func (rm *rollingMetric) Add(value float64) float64 {
 	}
 	rm.data[rm.index] = value
 
+	if len(rm.times) != dataLength {
+		rm.times = make([]time.Time, dataLength)
+	}
+	rm.times[rm.index] = time.Now()
+
 	var total float64
 	for i := 0; i < dataLength; i++ {
 		total += rm.data[i]
@@ -24,3 +36,62 @@ func (rm *rollingMetric) Add(value float64) float64 {
 
 	return float64(total) / float64(dataLength)
 }
+
+// Mean returns the average of the data points added so far, leaving out
+// slots in the data array that have never been filled.
+func (rm *rollingMetric) Mean() float64 {
+	return average(rm.filled())
+}
+
+// filled returns the data points in slots that have been added to.
+func (rm *rollingMetric) filled() []float64 {
+
+	values := make([]float64, 0, len(rm.times))
+	for i, added := range rm.times {
+		if !added.IsZero() {
+			values = append(values, rm.data[i])
+		}
+	}
+	return values
+}
+
+// Percentiles returns the p-th percentiles (0 to 100) of the data points
+// added so far, using the nearest-rank method. Slots that have never been
+// filled are left out, and the data is sorted once for all percentiles.
+func (rm *rollingMetric) Percentiles(ps ...float64) []float64 {
+
+	values := make([]float64, len(ps))
+
+	sorted := rm.filled()
+	dataLength := len(sorted)
+	if dataLength == 0 {
+		return values
+	}
+	sort.Float64s(sorted)
+
+	for i, p := range ps {
+		rank := int(math.Ceil(p / 100 * float64(dataLength)))
+		if rank < 1 {
+			rank = 1
+		}
+		if rank > dataLength {
+			rank = dataLength
+		}
+		values[i] = sorted[rank-1]
+	}
+
+	return values
+}
+
+// Since returns the data points added at or after t. Slots in the data array
+// that have never been filled are not included.
+func (rm *rollingMetric) Since(t time.Time) []float64 {
+
+	var values []float64
+	for i, added := range rm.times {
+		if !added.IsZero() && !added.Before(t) {
+			values = append(values, rm.data[i])
+		}
+	}
+	return values
+}
@@ -1,6 +1,9 @@
 package health
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestAddNewValue(t *testing.T) {
 	// Test Add() correctly adds a value to the data points array
@@ -39,3 +42,75 @@ func TestAddValuesToWrap(t *testing.T) {
 	}
 
 }
+
+func TestMean(t *testing.T) {
+	// Test Mean() averages only the filled slots of the data array.
+	//
+	var rm rollingMetric
+	rm.data = make([]float64, 10)
+
+	rm.Add(2)
+	rm.Add(4)
+
+	if m := rm.Mean(); m != 3 {
+		t.Errorf("Mean expected 3, got: %f", m)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	// Test Percentiles() returns the nearest-rank value from the data array
+	//
+
+	testValues := [10]float64{10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+	var rm rollingMetric
+	rm.data = make([]float64, len(testValues))
+
+	for _, value := range testValues {
+		rm.Add(value)
+	}
+
+	p := rm.Percentiles(50, 95, 0)
+	if p[0] != 5 {
+		t.Errorf("Percentile p50 expected 5, got: %f", p[0])
+	}
+	if p[1] != 10 {
+		t.Errorf("Percentile p95 expected 10, got: %f", p[1])
+	}
+	if p[2] != 1 {
+		t.Errorf("Percentile p0 expected 1, got: %f", p[2])
+	}
+}
+
+func TestPercentilesPartiallyFilled(t *testing.T) {
+	// Test slots that have never been filled don't count as zeros.
+	//
+	var rm rollingMetric
+	rm.data = make([]float64, 10)
+
+	rm.Add(100)
+
+	if p := rm.Percentiles(50); p[0] != 100 {
+		t.Errorf("Percentile p50 of a single value expected 100, got: %f", p[0])
+	}
+}
+
+func TestSince(t *testing.T) {
+	// Test Since() only returns data points added after the given time,
+	// and skips slots that were never filled.
+
+	testDataLength := 5
+
+	var rm rollingMetric
+	rm.data = make([]float64, testDataLength)
+
+	rm.Add(1.0)
+	rm.times[0] = time.Now().Add(-time.Hour) // pretend this is old
+	rm.Add(2.0)
+	rm.Add(3.0)
+
+	values := rm.Since(time.Now().Add(-time.Minute))
+	if len(values) != 2 || values[0] != 2.0 || values[1] != 3.0 {
+		t.Errorf("Since expected [2 3], got: %v", values)
+	}
+}
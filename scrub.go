@@ -0,0 +1,129 @@
+package health
+
+import "regexp"
+
+// ScrubFunc transforms a metric name before it is recorded. Returning
+// false rejects the metric, so it is never recorded.
+type ScrubFunc func(name string) (string, bool)
+
+// SetScrubber sets the hook applied to every metric name before it is
+// recorded, so names containing PII (emails, user IDs) can be redacted
+// or rejected. Call Scrub() to apply the hook to already recorded metrics.
+func (s *State) SetScrubber(scrubber ScrubFunc) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.scrubber = scrubber
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// RedactPatterns returns a ScrubFunc that replaces any part of a metric
+// name matching one of patterns with replacement.
+func RedactPatterns(replacement string, patterns ...*regexp.Regexp) ScrubFunc {
+	return func(name string) (string, bool) {
+		for _, p := range patterns {
+			name = p.ReplaceAllString(name, replacement)
+		}
+		return name, true
+	}
+}
+
+// RejectPatterns returns a ScrubFunc that rejects any metric name matching
+// one of patterns.
+func RejectPatterns(patterns ...*regexp.Regexp) ScrubFunc {
+	return func(name string) (string, bool) {
+		for _, p := range patterns {
+			if p.MatchString(name) {
+				return "", false
+			}
+		}
+		return name, true
+	}
+}
+
+// Scrub applies the scrubber to metrics that are already recorded.
+// Counters whose names scrub to the same value are summed, while gauges
+// and rolling metrics keep the first value seen.
+func (s *State) Scrub() {
+
+	mu.Lock() // enter CRITICAL SECTION
+	defer mu.Unlock()
+
+	if s.scrubber == nil {
+		return
+	}
+
+	metrics := make(map[string]int)
+	for name, value := range s.Metrics {
+		if name, ok := s.scrubName(name); ok {
+			metrics[name] += value
+		}
+	}
+
+	gauges := make(map[string]float64)
+	for name, value := range s.Gauges {
+		if name, ok := s.scrubName(name); ok {
+			if _, exists := gauges[name]; !exists {
+				gauges[name] = value
+			}
+		}
+	}
+
+	rollingMetrics := make(map[string]float64)
+	rollingMetricsData := make(map[string]*rollingMetric)
+	rollingPercentiles := make(map[string]map[string]float64)
+	ewmaMetrics := make(map[string]float64)
+	rawSamples := make(map[string][]Sample)
+	for name, value := range s.RollingMetrics {
+		if newName, ok := s.scrubName(name); ok {
+			if _, exists := rollingMetrics[newName]; !exists {
+				rollingMetrics[newName] = value
+				rollingMetricsData[newName] = s.rollingMetricsData[name]
+				if percentiles, ok := s.RollingPercentiles[name]; ok {
+					rollingPercentiles[newName] = percentiles
+				}
+				if ewma, ok := s.EWMAMetrics[name]; ok {
+					ewmaMetrics[newName] = ewma
+				}
+				if samples, ok := s.rawSamples[name]; ok {
+					rawSamples[newName] = samples
+				}
+			}
+		}
+	}
+
+	// keep nil maps nil, for consistent json output
+	if s.Metrics != nil {
+		s.Metrics = metrics
+	}
+	if s.Gauges != nil {
+		s.Gauges = gauges
+	}
+	if s.RollingMetrics != nil {
+		s.RollingMetrics = rollingMetrics
+		s.rollingMetricsData = rollingMetricsData
+	}
+	if s.RollingPercentiles != nil {
+		s.RollingPercentiles = rollingPercentiles
+	}
+	if s.EWMAMetrics != nil {
+		s.EWMAMetrics = ewmaMetrics
+	}
+	if s.rawSamples != nil {
+		s.rawSamples = rawSamples
+	}
+}
+
+// scrubName applies the scrubber to name, if there is one. Must be called
+// from within the critical section.
+func (s *State) scrubName(name string) (string, bool) {
+
+	if s.scrubber == nil {
+		return name, true
+	}
+
+	name, ok := s.scrubber(name)
+	if len(name) < 1 { // no name, no entry
+		return "", false
+	}
+	return name, ok
+}
@@ -0,0 +1,67 @@
+package health
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var emailPattern = regexp.MustCompile(`[a-z0-9]+@[a-z0-9.]+`)
+
+func TestRedactPatterns(t *testing.T) {
+	// Test metric names matching a PII pattern are redacted before they
+	// are recorded.
+	var s State
+	s.Info("test", 10)
+	s.SetScrubber(RedactPatterns("redacted", emailPattern))
+
+	s.IncrMetric("login.bob@example.com")
+	s.IncrMetric("login.alice@example.com")
+	result := s.Dump()
+
+	searchFor := "\"login.redacted\": 2"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Scrubber failed to redact metric names")
+	}
+}
+
+func TestRejectPatterns(t *testing.T) {
+	// Test metric names matching a PII pattern are not recorded at all.
+	//
+	var s State
+	s.Info("test", 10)
+	s.SetScrubber(RejectPatterns(emailPattern))
+
+	s.IncrMetric("login.bob@example.com")
+	s.SetGauge("sessions.bob@example.com", 1)
+	s.UpdateRollingMetric("latency.bob@example.com", 1)
+	result := s.Dump()
+
+	if strings.Index(result, "example.com") >= 0 {
+		t.Errorf("Scrubber failed to reject metric names")
+	}
+}
+
+func TestScrubExistingMetrics(t *testing.T) {
+	// Test Scrub() applies the scrubber to metrics recorded before the
+	// scrubber was set.
+	var s State
+	s.Info("test", 10)
+
+	s.IncrMetric("login.bob@example.com")
+	s.IncrMetric("logins")
+	s.UpdateRollingMetric("latency.bob@example.com", 1)
+
+	s.SetScrubber(RejectPatterns(emailPattern))
+	s.Scrub()
+	result := s.Dump()
+
+	if strings.Index(result, "example.com") >= 0 {
+		t.Errorf("Scrub failed to remove existing metric names")
+	}
+
+	searchFor := "\"logins\": 1"
+	if strings.Index(result, searchFor) < 0 {
+		t.Errorf("Scrub removed a metric that does not match")
+	}
+}
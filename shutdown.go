@@ -0,0 +1,34 @@
+package health
+
+import (
+	"context"
+	"fmt"
+)
+
+// Shutdown delivers any queued alert webhooks, giving up when ctx is done.
+// It returns an error saying how many webhooks were left undelivered if
+// the deadline passes first. Alerts firing after Shutdown are not sent.
+// It is safe to call more than once, and from several goroutines.
+func (s *State) Shutdown(ctx context.Context) error {
+
+	mu.Lock() // enter CRITICAL SECTION
+	if !s.shutdown {
+		s.shutdown = true
+		if s.alertQueue != nil {
+			close(s.alertQueue)
+		}
+	}
+	queue, done := s.alertQueue, s.alertDone
+	mu.Unlock() // end CRITICAL SECTION
+
+	if done == nil {
+		return nil // nothing was ever queued
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("health: shutdown %s with %d alert webhooks undelivered", ctx.Err(), len(queue))
+	}
+}
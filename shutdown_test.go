@@ -0,0 +1,107 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownDeliversQueuedAlerts(t *testing.T) {
+	// Test Shutdown waits for queued webhooks, and later calls are safe.
+	//
+	var mu sync.Mutex
+	delivered := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		delivered++
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	var s State
+	s.Info("test", 1)
+	s.AddAlertRule(AlertRule{Name: "slow", Metric: "latency", Op: ">", Threshold: 500, Webhook: server.URL})
+
+	s.UpdateRollingMetric("latency", 600)
+	s.UpdateRollingMetric("latency", 100)
+
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown returned error: %s", err)
+	}
+
+	mu.Lock()
+	if delivered != 2 {
+		t.Errorf("Shutdown expected 2 webhooks delivered, got: %d", delivered)
+	}
+	mu.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.Shutdown(context.Background()); err != nil {
+				t.Errorf("Repeated Shutdown returned error: %s", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	s.UpdateRollingMetric("latency", 600) // must not panic on the closed queue
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	// Test Shutdown gives up at the deadline and reports what is left.
+	//
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	var s State
+	s.Info("test", 1)
+	s.AddAlertRule(AlertRule{Name: "slow", Metric: "latency", Op: ">", Threshold: 500, Webhook: server.URL})
+
+	s.UpdateRollingMetric("latency", 600)
+	s.UpdateRollingMetric("latency", 100)
+	s.UpdateRollingMetric("latency", 600)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := s.Shutdown(ctx)
+	if err == nil || strings.Index(err.Error(), "undelivered") < 0 {
+		t.Errorf("Shutdown expected undelivered error, got: %v", err)
+	}
+}
+
+func TestPusherShutdown(t *testing.T) {
+	// Test Pusher.Shutdown makes a final push.
+	//
+	pushes := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pushes <- struct{}{}
+	}))
+	defer server.Close()
+
+	var s State
+	s.Info("worker-1", 10)
+
+	p := NewPusher(&s, server.URL)
+	p.Interval = time.Hour
+	p.Start()
+
+	if err := p.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Pusher Shutdown returned error: %s", err)
+	}
+
+	if len(pushes) != 1 {
+		t.Errorf("Pusher Shutdown expected 1 final push, got: %d", len(pushes))
+	}
+}
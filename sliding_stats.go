@@ -0,0 +1,49 @@
+package health
+
+import "time"
+
+// Stats summarises the data points of a rolling metric.
+type Stats struct {
+	Count int
+	Min   float64
+	Max   float64
+	Avg   float64
+}
+
+// SlidingStats returns stats for the data points of a rolling metric added
+// within the last window, for in-process adaptive logic such as dynamic
+// timeouts. Only the points held in the rolling sample are considered, so
+// the rolling data size needs to be large enough to cover the window.
+func (s *State) SlidingStats(name string, window time.Duration) Stats {
+
+	var stats Stats
+
+	mu.Lock() // enter CRITICAL SECTION
+	metric, ok := s.rollingMetricsData[name]
+	var values []float64
+	if ok {
+		values = metric.Since(time.Now().Add(-window))
+	}
+	mu.Unlock() // end CRITICAL SECTION
+
+	if len(values) == 0 {
+		return stats
+	}
+
+	var total float64
+	stats.Min = values[0]
+	stats.Max = values[0]
+	for _, v := range values {
+		if v < stats.Min {
+			stats.Min = v
+		}
+		if v > stats.Max {
+			stats.Max = v
+		}
+		total += v
+	}
+	stats.Count = len(values)
+	stats.Avg = total / float64(len(values))
+
+	return stats
+}
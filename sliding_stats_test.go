@@ -0,0 +1,35 @@
+package health
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSlidingStats(t *testing.T) {
+	// Test sliding stats only use the data points added so far, not the
+	// whole (zero-filled) rolling sample.
+	metricName := "responseTime"
+
+	var s State
+	s.Info("test", 10)
+
+	s.UpdateRollingMetric(metricName, 2.0)
+	s.UpdateRollingMetric(metricName, 4.0)
+
+	stats := s.SlidingStats(metricName, time.Minute)
+	if stats.Count != 2 || stats.Min != 2.0 || stats.Max != 4.0 || stats.Avg != 3.0 {
+		t.Errorf("SlidingStats expected {2 2 4 3}, got: %+v", stats)
+	}
+}
+
+func TestSlidingStatsUnknownMetric(t *testing.T) {
+	// Test an unknown metric returns empty stats.
+	//
+	var s State
+	s.Info("test", 10)
+
+	stats := s.SlidingStats("missing", time.Minute)
+	if stats.Count != 0 {
+		t.Errorf("SlidingStats for unknown metric expected no data, got: %+v", stats)
+	}
+}
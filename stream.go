@@ -0,0 +1,57 @@
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultStreamInterval is used when StreamHandler is given no interval.
+const defaultStreamInterval = 5 * time.Second
+
+// StreamHandler returns a handler that pushes a Dump() snapshot to the
+// client as a Server-Sent Event every interval, so dashboards can show live
+// metrics without polling. The stream ends when the client disconnects.
+func (s *State) StreamHandler(interval time.Duration) http.HandlerFunc {
+
+	if interval <= 0 {
+		interval = defaultStreamInterval
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			writeEvent(w, s.Dump())
+			flusher.Flush()
+
+			select {
+			case <-r.Context().Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+}
+
+// writeEvent writes data as a single SSE event, one data: field per line.
+func writeEvent(w http.ResponseWriter, data string) {
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,37 @@
+package health
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamHandler(t *testing.T) {
+	// Test the stream sends Dump() snapshots as SSE events until the
+	// client goes away.
+	var s State
+	s.Info("test", 10)
+	s.IncrMetric("myMetric")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/health/stream", nil).WithContext(ctx)
+	s.StreamHandler(10*time.Millisecond)(rec, req)
+
+	if rec.Header().Get("Content-Type") != "text/event-stream" {
+		t.Errorf("Stream has wrong content type: %s", rec.Header().Get("Content-Type"))
+	}
+
+	body := rec.Body.String()
+	if strings.Count(body, "data: {\n") < 2 {
+		t.Errorf("Stream expected several events, got: %s", body)
+	}
+
+	if strings.Index(body, "data:         \"myMetric\": 1\n") < 0 {
+		t.Errorf("Stream event missing metric")
+	}
+}
@@ -0,0 +1,28 @@
+package health
+
+import "fmt"
+
+// SetStrict turns strict mode on or off. In strict mode, mistakes that are
+// normally ignored (empty metric names, out of range settings, values that
+// can't be output as JSON) panic instead, so integration bugs are caught in
+// development and CI rather than showing up as missing data in production.
+// Strict mode can also be turned on with HEALTH_STRICT=true, read by Info().
+func (s *State) SetStrict(strict bool) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	s.strict = strict
+	mu.Unlock() // end CRITICAL SECTION
+}
+
+// strictf panics in strict mode, and does nothing otherwise. Must be called
+// from outside the critical section.
+func (s *State) strictf(format string, v ...interface{}) {
+
+	mu.Lock() // enter CRITICAL SECTION
+	strict := s.strict
+	mu.Unlock() // end CRITICAL SECTION
+
+	if strict {
+		panic(fmt.Sprintf(format, v...))
+	}
+}
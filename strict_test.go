@@ -0,0 +1,60 @@
+package health
+
+import (
+	"math"
+	"os"
+	"testing"
+)
+
+// expectPanic fails the test if f does not panic.
+func expectPanic(t *testing.T, name string, f func()) {
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s expected to panic in strict mode", name)
+		}
+	}()
+	f()
+}
+
+func TestStrictModePanics(t *testing.T) {
+	// Test ignored mistakes panic in strict mode.
+	//
+	var s State
+	s.Info("test", 10)
+	s.SetStrict(true)
+
+	expectPanic(t, "IncrMetric", func() { s.IncrMetric("") })
+	expectPanic(t, "SetGauge", func() { s.SetGauge("", 1) })
+	expectPanic(t, "UpdateRollingMetric", func() { s.UpdateRollingMetric("", 1) })
+	expectPanic(t, "UpdateRollingMetric NaN", func() { s.UpdateRollingMetric("latency", math.NaN()) })
+	expectPanic(t, "SetEWMAAlpha", func() { s.SetEWMAAlpha(2) })
+	expectPanic(t, "SetPercentiles", func() { s.SetPercentiles(101) })
+	expectPanic(t, "Info", func() { s.Info("", 10) })
+}
+
+func TestStrictModeOff(t *testing.T) {
+	// Test the same mistakes are ignored when strict mode is off.
+	//
+	var s State
+	s.Info("test", 10)
+
+	s.IncrMetric("")
+	s.SetEWMAAlpha(2)
+
+	if s.Metrics != nil || s.ewmaAlpha != 0 {
+		t.Errorf("Mistakes were not ignored with strict mode off")
+	}
+}
+
+func TestStrictModeFromEnv(t *testing.T) {
+	// Test HEALTH_STRICT turns strict mode on.
+	//
+	os.Setenv("HEALTH_STRICT", "true")
+	defer os.Unsetenv("HEALTH_STRICT")
+
+	var s State
+	s.Info("test", 10)
+
+	expectPanic(t, "IncrMetric", func() { s.IncrMetric("") })
+}
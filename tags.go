@@ -0,0 +1,46 @@
+package health
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// IncrMetricWithTags increments a counter metric for one combination of
+// tags, for example:
+//
+//	s.IncrMetricWithTags("requests", map[string]string{"route": "/api", "status": "200"})
+//
+// Each combination is its own metric, shown in Dump() as
+// requests{route="/api",status="200"}.
+func (s *State) IncrMetricWithTags(name string, tags map[string]string) {
+	s.IncrMetric(taggedName(name, tags))
+}
+
+// UpdateRollingMetricWithTags adds a data point to a rolling metric for one
+// combination of tags, in the same way as IncrMetricWithTags.
+func (s *State) UpdateRollingMetricWithTags(name string, tags map[string]string, value float64) {
+	s.UpdateRollingMetric(taggedName(name, tags), value)
+}
+
+// taggedName encodes tags into the metric name, with keys sorted so the
+// same tags always give the same name.
+func taggedName(name string, tags map[string]string) string {
+
+	if len(name) < 1 || len(tags) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+strconv.Quote(tags[k]))
+	}
+
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
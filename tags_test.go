@@ -0,0 +1,65 @@
+package health
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIncrMetricWithTags(t *testing.T) {
+	// Test each tag combination is counted as its own metric, and tag
+	// order does not matter.
+	var s State
+	s.Info("test", 10)
+
+	s.IncrMetricWithTags("requests", map[string]string{"route": "/api", "status": "200"})
+	s.IncrMetricWithTags("requests", map[string]string{"status": "200", "route": "/api"})
+	s.IncrMetricWithTags("requests", map[string]string{"route": "/api", "status": "500"})
+
+	if s.Metrics[`requests{route="/api",status="200"}`] != 2 {
+		t.Errorf("Tagged metric increment failed")
+	}
+
+	if s.Metrics[`requests{route="/api",status="500"}`] != 1 {
+		t.Errorf("Tagged metric second combination failed")
+	}
+}
+
+func TestUpdateRollingMetricWithTags(t *testing.T) {
+	// Test a tagged rolling metric is recorded under its tagged name.
+	//
+	var s State
+	s.Info("test", 10)
+
+	s.UpdateRollingMetricWithTags("latency", map[string]string{"route": "/api"}, 1.0)
+
+	if s.RollingMetrics[`latency{route="/api"}`] != 0.1 {
+		t.Errorf("Tagged rolling metric update failed")
+	}
+}
+
+func TestWithPrefixAndTags(t *testing.T) {
+	// Test a prefixed recorder prefixes the name, not the tags.
+	//
+	var s State
+	s.Info("test", 10)
+
+	s.WithPrefix("libfoo_").IncrMetricWithTags("requests", map[string]string{"route": "/api"})
+	result := s.Dump()
+
+	if strings.Index(result, `libfoo_requests{route=\"/api\"}`) < 0 {
+		t.Errorf("Prefixed tagged metric increment failed")
+	}
+}
+
+func TestIncrMetricWithTagsIgnoresEmptyName(t *testing.T) {
+	// Test tags without a metric name are ignored.
+	//
+	var s State
+	s.Info("test", 10)
+
+	s.IncrMetricWithTags("", map[string]string{"route": "/api"})
+
+	if s.Metrics != nil {
+		t.Errorf("Tagged metric with empty name was recorded")
+	}
+}